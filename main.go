@@ -9,6 +9,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
 	"github.com/mvaldes14/twitch-bot/pkgs/secrets"
 	"github.com/mvaldes14/twitch-bot/pkgs/server"
 	"github.com/mvaldes14/twitch-bot/pkgs/telemetry"
@@ -56,6 +57,14 @@ func main() {
 	}
 	logger.Info("Environment variables validated successfully")
 
+	// Load the bot configuration, env vars override the file
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("Configuration validation failed - aborting startup", err)
+		os.Exit(1)
+	}
+	logger.Info("Configuration loaded for broadcaster " + cfg.Broadcaster.ID)
+
 	// Initialize OpenTelemetry
 	otelConfig := telemetry.GetConfigFromEnv()
 	if err := telemetry.InitOTEL(ctx, otelConfig); err != nil {
@@ -80,15 +89,15 @@ func main() {
 		}
 	}()
 
-	s := secrets.NewSecretService()
+	s := secrets.NewSecretService(cfg)
 	s.InitSecrets()
 
-	// Start background token renewal (cancelled on shutdown)
-	renewCtx, renewCancel := context.WithCancel(ctx)
-	s.StartTokenRenewal(renewCtx)
+	// Start background token renewal and subscription reconcile (cancelled on shutdown)
+	bgCtx, bgCancel := context.WithCancel(ctx)
+	s.StartTokenRenewal(bgCtx)
 
 	logger.Info("Starting server on port" + port)
-	srv := server.NewServer(port)
+	srv := server.NewServer(bgCtx, port, cfg)
 
 	// Channel to listen for interrupt signals
 	stop := make(chan os.Signal, 1)
@@ -105,8 +114,8 @@ func main() {
 	<-stop
 	logger.Info("Shutting down server gracefully...")
 
-	// Stop the token renewal goroutine
-	renewCancel()
+	// Stop the token renewal and reconcile goroutines
+	bgCancel()
 
 	// Graceful shutdown with timeout
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
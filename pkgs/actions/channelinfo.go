@@ -0,0 +1,376 @@
+// Package actions handles Twitch chat commands and actions
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
+	"github.com/mvaldes14/twitch-bot/pkgs/locale"
+	"github.com/mvaldes14/twitch-bot/pkgs/subscriptions"
+)
+
+const (
+	// maxTitleLength is the longest stream title Helix accepts
+	maxTitleLength = 140
+	// categorySearchSize is how many Helix search results are ranked by !game
+	categorySearchSize = 25
+)
+
+// channelInfoCommands are the built-in commands moderators use to change the stream title, category and tags
+func (a *Actions) channelInfoCommands() []Command {
+	return []Command{
+		{
+			Name:        "today",
+			Description: "Updates the stream title with the active preset",
+			Usage:       "<title>",
+			Permission:  PermissionModerator,
+			MinArgs:     1,
+			Handler:     a.today,
+		},
+		{
+			Name:        "title",
+			Description: "Updates only the stream title",
+			Usage:       "<title>",
+			Permission:  PermissionModerator,
+			MinArgs:     1,
+			Handler:     a.title,
+		},
+		{
+			Name:        "game",
+			Aliases:     []string{"category"},
+			Description: "Changes the stream category",
+			Usage:       "<name>",
+			Permission:  PermissionModerator,
+			MinArgs:     1,
+			Handler:     a.game,
+		},
+		{
+			Name:        "tags",
+			Description: "Shows, adds or removes stream tags",
+			Usage:       "[add|remove <tags>]",
+			Permission:  PermissionModerator,
+			Handler:     a.tags,
+		},
+		{
+			Name:        "preset",
+			Description: "Switches title, category, tags and language together",
+			Usage:       "[name] [title]",
+			Permission:  PermissionModerator,
+			Handler:     a.preset,
+		},
+	}
+}
+
+// channelUpdate is the body of Modify Channel Information, empty fields are left unchanged
+type channelUpdate struct {
+	GameID   string   `json:"game_id,omitempty"`
+	Title    string   `json:"title,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Language string   `json:"broadcaster_language,omitempty"`
+}
+
+// modifyChannel applies a channel information update with the broadcaster's token
+func (a *Actions) modifyChannel(ctx context.Context, channelID string, payload any) error {
+	if err := a.helixUserRequest(ctx, "update_channel", http.MethodPatch, "/channels?broadcaster_id="+channelID, channelID, payload, nil); err != nil {
+		return fmt.Errorf("%w: %w", errUpdateChannel, err)
+	}
+	return nil
+}
+
+// channelInfo returns the current title, category and tags of a channel
+func (a *Actions) channelInfo(ctx context.Context, channelID string) (subscriptions.ChannelInformation, error) {
+	var channels subscriptions.ChannelInformationResponse
+	if err := a.helixUserRequest(ctx, "get_channel", http.MethodGet, "/channels?broadcaster_id="+channelID, channelID, nil, &channels); err != nil {
+		return subscriptions.ChannelInformation{}, err
+	}
+	if len(channels.Data) == 0 {
+		return subscriptions.ChannelInformation{}, fmt.Errorf("no channel information for %s", channelID)
+	}
+	return channels.Data[0], nil
+}
+
+// renderTitle fills a preset title template with text. A template without {title} is a fixed title,
+// an empty result leaves the stream title unchanged
+func renderTitle(template, text string) (string, error) {
+	title := text
+	if template != "" {
+		if strings.Contains(template, "{title}") && text == "" {
+			return "", nil
+		}
+		title = strings.ReplaceAll(template, "{title}", text)
+	}
+	title = strings.TrimSpace(title)
+	if utf8.RuneCountInString(title) > maxTitleLength {
+		return "", errTitleTooLong
+	}
+	return title, nil
+}
+
+// applyPreset switches a channel to a preset, text fills the {title} of its title template
+func (a *Actions) applyPreset(ctx context.Context, inv Invocation, preset Preset, text string) error {
+	title, err := renderTitle(preset.Title, text)
+	if err != nil {
+		return usageError("%s", a.Catalog.Format(inv.Locale, locale.TitleTooLong, "{max}", strconv.Itoa(maxTitleLength)))
+	}
+	payload := channelUpdate{
+		GameID:   preset.CategoryID,
+		Title:    title,
+		Tags:     preset.Tags,
+		Language: preset.Language,
+	}
+	if payload.GameID == "" && preset.Category != "" {
+		category, err := a.findCategory(ctx, inv, preset.Category)
+		if err != nil {
+			return err
+		}
+		payload.GameID = category.ID
+	}
+	if err := a.modifyChannel(ctx, inv.ChannelID, payload); err != nil {
+		return err
+	}
+	if err := a.setActivePreset(inv.ChannelID, preset.Name); err != nil {
+		a.Log.Error("Failed to store active preset of channel "+inv.ChannelID, err)
+	}
+	a.Log.Info(fmt.Sprintf("[CHANNEL: PRESET] %s by %s in channel %s", preset.Name, inv.ChatterName, inv.ChannelID))
+	return nil
+}
+
+// today sets the stream title with the active preset's title template, category, tags and language: `!today <title>`
+func (a *Actions) today(ctx context.Context, inv Invocation) error {
+	// Only channels served by this bot can be updated
+	if _, ok := a.Config.Channel(inv.ChannelID); !ok {
+		return nil
+	}
+	preset, err := a.activePreset(inv.ChannelID)
+	if err != nil {
+		return a.commandFailed(inv, err)
+	}
+	if err := a.applyPreset(ctx, inv, preset, inv.RawArgs); err != nil {
+		return a.commandFailed(inv, err)
+	}
+	a.Log.Info("Channel updated successfully")
+	return nil
+}
+
+// title changes only the stream title, through the active preset's title template: `!title <title>`
+func (a *Actions) title(ctx context.Context, inv Invocation) error {
+	preset, err := a.activePreset(inv.ChannelID)
+	if err != nil {
+		return a.commandFailed(inv, err)
+	}
+	template := preset.Title
+	if !strings.Contains(template, "{title}") {
+		// A fixed preset title would ignore the text, !title always sets what it was given
+		template = ""
+	}
+	title, err := renderTitle(template, inv.RawArgs)
+	if err != nil {
+		return usageError("%s", a.Catalog.Format(inv.Locale, locale.TitleTooLong, "{max}", strconv.Itoa(maxTitleLength)))
+	}
+	if err := a.modifyChannel(ctx, inv.ChannelID, channelUpdate{Title: title}); err != nil {
+		return a.commandFailed(inv, err)
+	}
+	a.Log.Info(fmt.Sprintf("[CHANNEL: TITLE] %q by %s in channel %s", title, inv.ChatterName, inv.ChannelID))
+	return a.Respond(inv, a.Catalog.Format(inv.Locale, locale.TitleChanged, "{title}", title))
+}
+
+// game changes the stream category to the closest match of a Helix category search: `!game <name>`
+func (a *Actions) game(ctx context.Context, inv Invocation) error {
+	category, err := a.findCategory(ctx, inv, inv.RawArgs)
+	if err != nil {
+		return a.commandFailed(inv, err)
+	}
+	if err := a.modifyChannel(ctx, inv.ChannelID, channelUpdate{GameID: category.ID}); err != nil {
+		return a.commandFailed(inv, err)
+	}
+	a.Log.Info(fmt.Sprintf("[CHANNEL: GAME] %s by %s in channel %s", category.Name, inv.ChatterName, inv.ChannelID))
+	return a.Respond(inv, a.Catalog.Format(inv.Locale, locale.GameChanged, "{game}", category.Name))
+}
+
+// findCategory searches Helix for a category and picks the closest name, see bestCategory
+func (a *Actions) findCategory(ctx context.Context, inv Invocation, name string) (subscriptions.Category, error) {
+	query := url.Values{"query": {name}, "first": {strconv.Itoa(categorySearchSize)}}
+	var results subscriptions.CategoriesResponse
+	if err := a.helixUserRequest(ctx, "search_categories", http.MethodGet, "/search/categories?"+query.Encode(), inv.ChannelID, nil, &results); err != nil {
+		return subscriptions.Category{}, err
+	}
+	category, ok := bestCategory(name, results.Data)
+	if !ok {
+		return subscriptions.Category{}, usageError("%s", a.Catalog.Format(inv.Locale, locale.CategoryNotFound, "{category}", name))
+	}
+	return category, nil
+}
+
+// bestCategory ranks categories by how close their name is to query: an exact match, then a prefix,
+// then a substring, then the smallest edit distance. Within a rank the name closest in length wins,
+// remaining ties keep the Helix order
+func bestCategory(query string, categories []subscriptions.Category) (subscriptions.Category, bool) {
+	target := normalizeName(query)
+	best, bestScore := -1, 0
+	for i, category := range categories {
+		name := normalizeName(category.Name)
+		// Ranks are spaced so the length difference only breaks ties within one
+		extra := utf8.RuneCountInString(name) - utf8.RuneCountInString(target)
+		var score int
+		switch {
+		case name == target:
+			score = 0
+		case strings.HasPrefix(name, target):
+			score = 1000 + extra
+		case strings.Contains(name, target):
+			score = 2000 + extra
+		default:
+			distance := levenshtein(name, target)
+			// Distant names are only Helix guesses, they must be within a third of the query
+			if distance > max(2, utf8.RuneCountInString(target)/3) {
+				continue
+			}
+			score = 3000 + distance
+		}
+		if best < 0 || score < bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best < 0 {
+		return subscriptions.Category{}, false
+	}
+	return categories[best], true
+}
+
+// normalizeName lower cases a name and keeps only its letters and digits, so "Half-Life 2" matches "half life 2"
+func normalizeName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// levenshtein is the number of single character edits that turn a into b
+func levenshtein(a, b string) int {
+	source, target := []rune(a), []rune(b)
+	previous := make([]int, len(target)+1)
+	current := make([]int, len(target)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(source); i++ {
+		current[0] = i
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(target)]
+}
+
+// tags shows the stream tags or changes them: `!tags`, `!tags add <tags>`, `!tags remove <tags>`
+func (a *Actions) tags(ctx context.Context, inv Invocation) error {
+	info, err := a.channelInfo(ctx, inv.ChannelID)
+	if err != nil {
+		return a.commandFailed(inv, err)
+	}
+	if len(inv.Args) == 0 {
+		return a.Respond(inv, a.Catalog.Format(inv.Locale, locale.TagsCurrent, "{tags}", strings.Join(info.Tags, ", ")))
+	}
+	if len(inv.Args) < 2 {
+		return usageError("%s", a.Catalog.Format(inv.Locale, locale.MissingArguments))
+	}
+
+	// A non-nil slice so removing the last tag clears them instead of leaving them unchanged
+	tags := append([]string{}, info.Tags...)
+	switch strings.ToLower(inv.Arg(0)) {
+	case "add":
+		for _, tag := range inv.Args[1:] {
+			tag = strings.TrimPrefix(tag, "#")
+			if !config.ValidTag(tag) {
+				return usageError("%s", a.Catalog.Format(inv.Locale, locale.InvalidTag, "{tag}", tag, "{max}", strconv.Itoa(config.MaxTagLength)))
+			}
+			if indexFold(tags, tag) < 0 {
+				tags = append(tags, tag)
+			}
+		}
+		if len(tags) > config.MaxTags {
+			return usageError("%s", a.Catalog.Format(inv.Locale, locale.TooManyTags, "{max}", strconv.Itoa(config.MaxTags)))
+		}
+	case "remove":
+		for _, tag := range inv.Args[1:] {
+			if i := indexFold(tags, strings.TrimPrefix(tag, "#")); i >= 0 {
+				tags = append(tags[:i], tags[i+1:]...)
+			}
+		}
+	default:
+		return usageError("%s", a.Catalog.Format(inv.Locale, locale.MissingArguments))
+	}
+
+	payload := struct {
+		Tags []string `json:"tags"`
+	}{Tags: tags}
+	if err := a.modifyChannel(ctx, inv.ChannelID, payload); err != nil {
+		return a.commandFailed(inv, err)
+	}
+	a.Log.Info(fmt.Sprintf("[CHANNEL: TAGS] %s by %s in channel %s", strings.Join(tags, ","), inv.ChatterName, inv.ChannelID))
+	return a.Respond(inv, a.Catalog.Format(inv.Locale, locale.TagsChanged, "{tags}", strings.Join(tags, ", ")))
+}
+
+// indexFold returns the index of value in values ignoring case, -1 when it is missing
+func indexFold(values []string, value string) int {
+	for i, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return i
+		}
+	}
+	return -1
+}
+
+// preset lists the presets of the channel or switches to one: `!preset [name] [title]`
+func (a *Actions) preset(ctx context.Context, inv Invocation) error {
+	presets, err := a.ListPresets(inv.ChannelID)
+	if err != nil {
+		return a.commandFailed(inv, err)
+	}
+	names := []string{DefaultPreset}
+	for _, preset := range presets {
+		names = append(names, preset.Name)
+	}
+
+	if len(inv.Args) == 0 {
+		active, err := a.activePreset(inv.ChannelID)
+		if err != nil {
+			return a.commandFailed(inv, err)
+		}
+		return a.Respond(inv, a.Catalog.Format(inv.Locale, locale.PresetCurrent,
+			"{preset}", active.Name,
+			"{presets}", strings.Join(names, ", "),
+		))
+	}
+
+	preset, err := a.GetPreset(inv.ChannelID, inv.Arg(0))
+	if err != nil {
+		if !errors.Is(err, ErrPresetNotFound) {
+			return a.commandFailed(inv, err)
+		}
+		return usageError("%s", a.Catalog.Format(inv.Locale, locale.PresetNotFound,
+			"{preset}", inv.Arg(0),
+			"{presets}", strings.Join(names, ", "),
+		))
+	}
+	if err := a.applyPreset(ctx, inv, preset, strings.Join(inv.Args[1:], " ")); err != nil {
+		return a.commandFailed(inv, err)
+	}
+	return a.Respond(inv, a.Catalog.Format(inv.Locale, locale.PresetApplied, "{preset}", preset.Name))
+}
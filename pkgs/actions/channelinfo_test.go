@@ -0,0 +1,67 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/subscriptions"
+)
+
+func categories(names ...string) []subscriptions.Category {
+	list := make([]subscriptions.Category, 0, len(names))
+	for _, name := range names {
+		list = append(list, subscriptions.Category{ID: name, Name: name})
+	}
+	return list
+}
+
+func TestBestCategory(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		categories []subscriptions.Category
+		want       string
+		wantOK     bool
+	}{
+		{name: "no results", query: "minecraft", categories: nil},
+		{name: "exact match wins over Helix order", query: "minecraft", categories: categories("Minecraft Dungeons", "Minecraft"), want: "Minecraft", wantOK: true},
+		{name: "exact match ignores case and punctuation", query: "half life 2", categories: categories("Half-Life: Alyx", "Half-Life 2"), want: "Half-Life 2", wantOK: true},
+		{name: "shortest prefix", query: "minecr", categories: categories("Minecraft Dungeons", "Minecraft Legends", "Minecraft"), want: "Minecraft", wantOK: true},
+		{name: "prefix beats substring", query: "just chat", categories: categories("Not Just Chatting", "Just Chatting"), want: "Just Chatting", wantOK: true},
+		{name: "substring", query: "souls", categories: categories("Dark Souls III", "Demon's Souls"), want: "Demon's Souls", wantOK: true},
+		{name: "typo within distance", query: "minecarft", categories: categories("Minecraft"), want: "Minecraft", wantOK: true},
+		{name: "smallest distance wins", query: "fortnit", categories: categories("Fortune", "Fortnite"), want: "Fortnite", wantOK: true},
+		{name: "unrelated guesses are dropped", query: "elden ring", categories: categories("Eternal Return", "Golden Eye"), wantOK: false},
+		{name: "ties keep Helix order", query: "doom", categories: categories("Doom 3", "Doom 2"), want: "Doom 3", wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := bestCategory(tt.query, tt.categories)
+			if ok != tt.wantOK {
+				t.Fatalf("bestCategory(%q) ok = %v, want %v", tt.query, ok, tt.wantOK)
+			}
+			if got.Name != tt.want {
+				t.Errorf("bestCategory(%q) = %q, want %q", tt.query, got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"minecraft", "minecraft", 0},
+		{"minecarft", "minecraft", 2},
+		{"kitten", "sitting", 3},
+		{"pokémon", "pokemon", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Package actions handles Twitch chat commands and actions
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
+	"github.com/mvaldes14/twitch-bot/pkgs/locale"
+	"github.com/mvaldes14/twitch-bot/pkgs/subscriptions"
+	"github.com/mvaldes14/twitch-bot/pkgs/telemetry"
+)
+
+const (
+	// ClipSourceCommand marks clips requested with !clip
+	ClipSourceCommand = "command"
+	// ClipSourceReward marks clips requested with a channel points reward
+	ClipSourceReward = "reward"
+	// clipPollInterval is how often Get Clips is checked while Twitch processes a new clip
+	clipPollInterval = 2 * time.Second
+	// clipTimeout is how long Twitch may take before a clip is considered failed, as documented for Create Clip
+	clipTimeout = 15 * time.Second
+	// maxStreamClips is how many clips of a stream are kept for the end-of-stream summary
+	maxStreamClips = 100
+)
+
+var errClipNotCreated = errors.New("twitch did not return a clip")
+
+// Clip is a clip created by the bot during a stream
+type Clip struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// RequestedBy is the chatter who ran !clip or redeemed the reward
+	RequestedBy string    `json:"requested_by"`
+	Source      string    `json:"source"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// clipsKey is the Redis list of the clips created during a channel's current stream, newest first
+func clipsKey(channelID string) string {
+	return "clips:" + channelID
+}
+
+// clip clips the stream for a chatter: `!clip`
+func (a *Actions) clip(ctx context.Context, inv Invocation) error {
+	return a.commandFailed(inv, a.startClip(ctx, inv, ClipSourceCommand))
+}
+
+// ClipReward clips the stream for the viewer who redeemed a channel points reward, the link is sent to chat
+func (a *Actions) ClipReward(ctx context.Context, channelID, userName string) error {
+	inv := Invocation{
+		Name:        "clip",
+		ChannelID:   channelID,
+		ChatterName: userName,
+		Locale:      a.commandLocale("clip", channelID),
+	}
+	return a.startClip(ctx, inv, ClipSourceReward)
+}
+
+// startClip creates a clip with the broadcaster's token and publishes it in the background once Twitch finished it,
+// so the chat event is not held up while the clip processes
+func (a *Actions) startClip(ctx context.Context, inv Invocation, source string) error {
+	query := url.Values{"broadcaster_id": {inv.ChannelID}}
+	var created subscriptions.CreateClipResponse
+	if err := a.helixUserRequest(ctx, "create_clip", http.MethodPost, "/clips?"+query.Encode(), inv.ChannelID, nil, &created); err != nil {
+		telemetry.IncrementClip(ctx, source, "error")
+		return err
+	}
+	if len(created.Data) == 0 {
+		telemetry.IncrementClip(ctx, source, "error")
+		return errClipNotCreated
+	}
+	clipID := created.Data[0].ID
+	a.Log.Info(fmt.Sprintf("[CLIP: CREATED] %s by %s in channel %s", clipID, inv.ChatterName, inv.ChannelID))
+
+	go a.publishClip(inv, clipID, source)
+	return nil
+}
+
+// publishClip waits for a clip to finish, then posts it in chat, forwards it to Discord and records it for the
+// end-of-stream summary
+func (a *Actions) publishClip(inv Invocation, clipID, source string) {
+	ctx, cancel := context.WithTimeout(context.Background(), clipTimeout+httpTimeout)
+	defer cancel()
+
+	finished, err := a.awaitClip(ctx, inv.ChannelID, clipID)
+	if err != nil {
+		a.Log.Error("Clip "+clipID+" was not finished", err)
+		telemetry.IncrementClip(ctx, source, "timeout")
+		_ = a.Respond(inv, a.Catalog.Format(inv.Locale, locale.ClipFailed, "{user}", inv.ChatterName))
+		return
+	}
+	telemetry.IncrementClip(ctx, source, "success")
+	a.Log.Info(fmt.Sprintf("[CLIP: READY] %s by %s in channel %s", finished.URL, inv.ChatterName, inv.ChannelID))
+	_ = a.Respond(inv, a.Catalog.Format(inv.Locale, locale.ClipCreated, "{user}", inv.ChatterName, "{url}", finished.URL))
+
+	channelName := inv.ChannelID
+	if channel, ok := a.Config.Channel(inv.ChannelID); ok {
+		channelName = clipChannelName(channel)
+	}
+	shared := a.Translate(inv.ChannelID, locale.ClipShared,
+		"{user}", inv.ChatterName,
+		"{channel}", channelName,
+		"{url}", finished.URL,
+	)
+	if err := a.Notification.SendNotification(shared); err != nil {
+		a.Log.Error("Failed to forward clip to discord", err)
+	}
+
+	a.recordClip(inv.ChannelID, Clip{
+		ID:          finished.ID,
+		URL:         finished.URL,
+		RequestedBy: inv.ChatterName,
+		Source:      source,
+		CreatedAt:   time.Now(),
+	})
+}
+
+// awaitClip polls Get Clips until the clip shows up, Twitch needs a few seconds to process it
+func (a *Actions) awaitClip(ctx context.Context, channelID, clipID string) (subscriptions.Clip, error) {
+	deadline := time.Now().Add(clipTimeout)
+	query := url.Values{"id": {clipID}}
+	for {
+		select {
+		case <-ctx.Done():
+			return subscriptions.Clip{}, ctx.Err()
+		case <-time.After(clipPollInterval):
+		}
+		var clips subscriptions.ClipsResponse
+		err := a.helixUserRequest(ctx, "get_clips", http.MethodGet, "/clips?"+query.Encode(), channelID, nil, &clips)
+		if err == nil && len(clips.Data) > 0 && clips.Data[0].URL != "" {
+			return clips.Data[0], nil
+		}
+		if err != nil {
+			a.Log.Error("Failed to check clip "+clipID, err)
+		}
+		if time.Now().After(deadline) {
+			return subscriptions.Clip{}, fmt.Errorf("%w within %s", errClipNotCreated, clipTimeout)
+		}
+	}
+}
+
+// recordClip stores a clip for the end-of-stream summary
+func (a *Actions) recordClip(channelID string, clip Clip) {
+	value, err := json.Marshal(clip)
+	if err != nil {
+		a.Log.Error("Failed to marshal clip", err)
+		return
+	}
+	if err := a.Cache.PushCapped(clipsKey(channelID), string(value), maxStreamClips); err != nil {
+		a.Log.Error("Failed to record clip "+clip.ID, err)
+	}
+}
+
+// StreamClips returns the clips created during the current or last stream of a channel, oldest first
+func (a *Actions) StreamClips(channelID string) ([]Clip, error) {
+	values, err := a.Cache.GetList(clipsKey(channelID), maxStreamClips)
+	if err != nil {
+		return nil, err
+	}
+	clips := make([]Clip, 0, len(values))
+	for i := len(values) - 1; i >= 0; i-- {
+		var clip Clip
+		if err := json.Unmarshal([]byte(values[i]), &clip); err != nil {
+			a.Log.Error("Skipping unreadable clip", err)
+			continue
+		}
+		clips = append(clips, clip)
+	}
+	return clips, nil
+}
+
+// StreamSummary renders the clips of the last stream for Discord, empty when no clips were made
+func (a *Actions) StreamSummary(channel config.Channel) (string, error) {
+	clips, err := a.StreamClips(channel.ID)
+	if err != nil || len(clips) == 0 {
+		return "", err
+	}
+	urls := make([]string, 0, len(clips))
+	for _, clip := range clips {
+		urls = append(urls, clip.URL)
+	}
+	return a.Translate(channel.ID, locale.StreamClips,
+		"{channel}", clipChannelName(channel),
+		"{count}", strconv.Itoa(len(clips)),
+		"{clips}", strings.Join(urls, " "),
+	), nil
+}
+
+// clipChannelName is the channel name shown with clips on Discord, the ID when the channel has no name
+func clipChannelName(channel config.Channel) string {
+	if channel.Name != "" {
+		return channel.Name
+	}
+	return channel.ID
+}
+
+// resetClips forgets the clips of the previous stream when a new one starts
+func (a *Actions) resetClips(channelID string) {
+	if err := a.Cache.Delete(clipsKey(channelID)); err != nil {
+		a.Log.Error("Failed to reset clips of channel "+channelID, err)
+	}
+}
@@ -8,9 +8,16 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/mvaldes14/twitch-bot/pkgs/cache"
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
+	"github.com/mvaldes14/twitch-bot/pkgs/locale"
+	"github.com/mvaldes14/twitch-bot/pkgs/notifications"
 	"github.com/mvaldes14/twitch-bot/pkgs/secrets"
 	"github.com/mvaldes14/twitch-bot/pkgs/spotify"
 	"github.com/mvaldes14/twitch-bot/pkgs/subscriptions"
@@ -18,16 +25,17 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 )
 
-const (
-	messageEndpoint  = "https://api.twitch.tv/helix/chat/messages"
-	channelsEndpoint = "https://api.twitch.tv/helix/channels"
-	userID           = "1792311"
-	softwareID       = 1469308723
-)
+const messageEndpoint = "https://api.twitch.tv/helix/chat/messages"
+
+// sentMessageTTL is how long a message posted as the broadcaster is remembered, well past its chat notification
+const sentMessageTTL = 5 * time.Minute
 
 var (
 	errUpdateChannel = errors.New("updating channel info")
+	errTitleTooLong  = errors.New("title too long")
 	errUnauthorized  = errors.New("401 unauthorized: token expired")
+	// ErrMessageDropped is returned when Twitch accepts the request but does not post the message, e.g. AutoMod
+	ErrMessageDropped = errors.New("message dropped by Twitch")
 )
 
 // Actions handles all Twitch chat actions and commands
@@ -35,81 +43,174 @@ type Actions struct {
 	Log     *telemetry.CustomLogger
 	Secrets *secrets.SecretService
 	Spotify *spotify.Spotify
+	Config  *config.Config
+	Cache   *cache.Service
+	// Notification forwards clips to Discord
+	Notification *notifications.NotificationService
+	// Catalog holds the templates of the bot's own messages, see Locale for how a channel's locale is picked
+	Catalog *locale.Catalog
+	// Registries hold the commands of every channel, keyed by broadcaster ID
+	Registries map[string]*Registry
+	locales    sessionLocales
+	// blocklist holds the compiled moderation.blocklist.patterns
+	blocklist []*regexp.Regexp
+	// queue holds chat messages until the worker delivers them within limiter, queueMu keeps the parts of a
+	// split message together
+	queue      chan outboundMessage
+	queueMu    sync.Mutex
+	limiter    sendLimiter
+	httpClient *http.Client
 }
 
-// NewActions creates a new Actions instance
-func NewActions(secretService *secrets.SecretService) *Actions {
+// NewActions creates a new Actions instance for the configured channels
+func NewActions(secretService *secrets.SecretService, cfg *config.Config) *Actions {
 	logger := telemetry.NewLogger("actions")
 	spotifyClient := spotify.NewSpotify()
-	return &Actions{
-		Log:     logger,
-		Secrets: secretService,
-		Spotify: spotifyClient,
+	a := &Actions{
+		Log:          logger,
+		Secrets:      secretService,
+		Spotify:      spotifyClient,
+		Config:       cfg,
+		Cache:        cache.NewCacheService(),
+		Notification: notifications.NewNotificationService(cfg.Notifications.GotifyURL),
+		Catalog:      locale.NewCatalog(cfg.Locale.Default, cfg.Locale.Messages),
+		Registries:   map[string]*Registry{},
+		locales:      sessionLocales{active: map[string]string{}},
+		queue:        make(chan outboundMessage, messageQueueSize),
+		httpClient:   &http.Client{Timeout: httpTimeout},
 	}
+	a.blocklist = a.compileBlocklist()
+	for _, channel := range cfg.AllChannels() {
+		a.Registries[channel.ID] = a.newChannelRegistry(channel)
+	}
+	return a
 }
 
-// ParseMessage Parses the incoming messages from stream
+// ParseMessage Parses the incoming messages from stream and runs the command they invoke
 func (a *Actions) ParseMessage(msg subscriptions.ChatMessageEvent) {
 	ctx := context.Background()
 	payload := fmt.Sprintf("%s: %s", msg.Event.ChatterUserName, msg.Event.Message.Text)
 	a.Log.Chat(payload)
-	// Simple commands
-	switch msg.Event.Message.Text {
-	case "!commands":
-		telemetry.IncrementCommandExecuted(ctx, "commands")
-		_ = a.SendMessage("!github, !dotfiles, !song, !social, !blog, !youtube ")
-	case "!github":
-		telemetry.IncrementCommandExecuted(ctx, "github")
-		_ = a.SendMessage("https://links.mvaldes.dev/gh")
-	case "!dotfiles":
-		telemetry.IncrementCommandExecuted(ctx, "dotfiles")
-		_ = a.SendMessage("https://links.mvaldes.dev/dotfiles")
-	case "!test":
-		telemetry.IncrementCommandExecuted(ctx, "test")
-		_ = a.SendMessage("Test Me")
-	case "!social":
-		telemetry.IncrementCommandExecuted(ctx, "social")
-		_ = a.SendMessage("https://links.mvaldes.dev/twitter")
-	case "!blog":
-		telemetry.IncrementCommandExecuted(ctx, "blog")
-		_ = a.SendMessage("https://mvaldes.dev")
-	case "!discord":
-		telemetry.IncrementCommandExecuted(ctx, "discord")
-		_ = a.SendMessage("https://links.mvaldes.dev/discord")
-	case "!youtube":
-		telemetry.IncrementCommandExecuted(ctx, "youtube")
-		_ = a.SendMessage("https://links.mvaldes.dev/youtube")
-	case "!song":
-		telemetry.IncrementCommandExecuted(ctx, "song")
-		song, err := a.Spotify.GetSong()
+	// The bot never answers its own messages or counts them towards timers
+	if a.sentByBot(msg) {
+		return
+	}
+	a.countChatMessage(msg.Event.BroadcasterUserID)
+
+	inv, ok, parseErr := parseInvocation(a.Config.CommandPrefix, msg)
+	if !ok {
+		return
+	}
+	registry, ok := a.Registries[inv.ChannelID]
+	if !ok {
+		return
+	}
+
+	// Built-in and configured commands take precedence over custom ones
+	cmd, ok := registry.Lookup(inv.Name)
+	if !ok {
+		custom, err := a.GetCustomCommand(inv.ChannelID, inv.Name)
 		if err != nil {
-			a.Log.Error("Failed to get current song", err)
-			_ = a.SendMessage("Sorry, couldn't get the current song")
+			if !errors.Is(err, ErrCommandNotFound) {
+				a.Log.Error("Failed to look up custom command !"+inv.Name, err)
+			}
 			return
 		}
-		if song.Item.Name == "" || len(song.Item.Artists) == 0 {
-			_ = a.SendMessage("No song currently playing")
-			return
+		permission, _ := ParsePermission(custom.Permission)
+		cmd = &Command{Name: custom.Name, Response: custom.Response, Permission: permission, NoReply: custom.Reply != nil && !*custom.Reply}
+	}
+	inv.Reply = !cmd.NoReply
+	inv.Locale = a.commandLocale(cmd.Name, inv.ChannelID)
+
+	if inv.Permission < cmd.Permission {
+		a.Log.Info(fmt.Sprintf("[COMMAND: DENIED] !%s requires %s, %s is %s", cmd.Name, cmd.Permission, inv.ChatterName, inv.Permission))
+		telemetry.IncrementCommandDenied(ctx, cmd.Name, cmd.Permission.String())
+		return
+	}
+	if parseErr == nil && len(inv.Args) < cmd.MinArgs {
+		parseErr = usageError("%s", a.Catalog.Format(inv.Locale, locale.MissingArguments))
+	}
+	if parseErr != nil {
+		a.reportUsage(cmd, inv, parseErr)
+		return
+	}
+	if a.onCooldown(ctx, cmd, inv) {
+		return
+	}
+
+	telemetry.IncrementCommandExecuted(ctx, cmd.Name)
+	a.Log.Info(fmt.Sprintf("Running command !%s for %s", cmd.Name, inv.ChatterName))
+	if cmd.Handler != nil {
+		if err := cmd.Handler(ctx, inv); err != nil {
+			if errors.Is(err, ErrUsage) {
+				a.reportUsage(cmd, inv, err)
+				return
+			}
+			a.Log.Error("Command !"+cmd.Name+" failed", err)
 		}
-		songMsg := fmt.Sprintf("Now playing: %v - %v", song.Item.Artists[0].Name, song.Item.Name)
-		a.Log.Info(songMsg)
-		_ = a.SendMessage(songMsg)
+		return
 	}
-	// Complex commands
-	if strings.HasPrefix(msg.Event.Message.Text, "!today") {
-		telemetry.IncrementCommandExecuted(ctx, "today")
-		a.Log.Info("Today command running")
-		a.updateChannel(msg)
+	// Replies go to the channel the message was sent in
+	_ = a.Respond(inv, a.renderResponse(cmd, inv))
+}
+
+// reportUsage tells the chatter how a command is used after a usage error
+func (a *Actions) reportUsage(cmd *Command, inv Invocation, err error) {
+	a.Log.Info(fmt.Sprintf("[COMMAND: USAGE] !%s from %s: %v", cmd.Name, inv.ChatterName, err))
+	reason := strings.TrimPrefix(err.Error(), ErrUsage.Error()+": ")
+	if cmd.Usage == "" {
+		_ = a.Respond(inv, a.Catalog.Format(inv.Locale, locale.Usage, "{user}", inv.ChatterName, "{reason}", reason))
+		return
 	}
+	_ = a.Respond(inv, a.Catalog.Format(inv.Locale, locale.UsageHint,
+		"{user}", inv.ChatterName,
+		"{reason}", reason,
+		"{usage}", a.Config.CommandPrefix+cmd.Name+" "+cmd.Usage,
+	))
 }
 
-// SendMessage sends a message to the Twitch chat room.
-// On 401 Unauthorized, it triggers a token refresh and retries once.
-// Validates headers exist before making API call
-func (a *Actions) SendMessage(text string) error {
-	ctx := context.Background()
+// currentSong replies with the song playing on Spotify
+func (a *Actions) currentSong(_ context.Context, inv Invocation) error {
+	song, err := a.Spotify.GetSong()
+	if err != nil {
+		a.Log.Error("Failed to get current song", err)
+		return a.Respond(inv, a.Catalog.Format(inv.Locale, locale.SongError))
+	}
+	if song.Item.Name == "" || len(song.Item.Artists) == 0 {
+		return a.Respond(inv, a.Catalog.Format(inv.Locale, locale.SongNone))
+	}
+	songMsg := a.Catalog.Format(inv.Locale, locale.SongPlaying, "{artist}", song.Item.Artists[0].Name, "{song}", song.Item.Name)
+	a.Log.Info(songMsg)
+	return a.Respond(inv, songMsg)
+}
+
+// SendMessage queues a message for the chat room of the given channel.
+// Messages over the Twitch length limit are split into numbered parts and delivered in order
+// by the worker started with StartMessageQueue, ErrQueueFull is returned when the queue cannot take more messages.
+func (a *Actions) SendMessage(channelID, text string) error {
+	return a.Reply(channelID, "", text)
+}
+
+// Reply queues a message threaded as a reply to parentMessageID, a regular message when it is empty.
+// Every part of a split message replies to the same parent, empty text sends nothing.
+func (a *Actions) Reply(channelID, parentMessageID, text string) error {
+	parts := splitMessage(text, maxMessageLength)
+	if len(parts) == 0 {
+		return nil
+	}
+	if err := a.enqueueMessages(channelID, parentMessageID, parts); err != nil {
+		a.Log.Error("Cannot queue message for channel "+channelID, err)
+		return err
+	}
+	return nil
+}
+
+// deliverMessage sends a queued message to Twitch.
+// On 401 Unauthorized, it triggers a token refresh and retries once, 429, 5xx and network errors are retried with backoff.
+func (a *Actions) deliverMessage(ctx context.Context, msg outboundMessage) error {
 	_, span := telemetry.StartExternalSpan(ctx, "twitch.send_message", "twitch", "send_message")
 	defer span.End()
+	telemetry.AddSpanAttributes(span, attribute.String("twitch.channel_id", msg.channelID))
 
 	// Validate Twitch API credentials before attempting message send
 	_, err := a.Secrets.BuildSecretHeaders()
@@ -117,46 +218,73 @@ func (a *Actions) SendMessage(text string) error {
 		errMsg := fmt.Errorf("cannot send message to Twitch chat without valid API credentials: %w", err)
 		a.Log.Error("Cannot send message to Twitch chat - API credentials missing", errMsg)
 		telemetry.RecordError(span, errMsg)
+		telemetry.IncrementMessageSent(ctx, "error")
 		return errMsg
 	}
 
-	err = a.sendMessageInternal(ctx, text)
-	if err == nil {
-		telemetry.IncrementMessageSent(ctx, "success")
-		return nil
-	}
+	refreshed := false
+	for attempt := 1; ; attempt++ {
+		a.limiter.record(time.Now())
+		err = a.sendMessageInternal(ctx, msg)
+		if err == nil {
+			telemetry.IncrementMessageSent(ctx, "success")
+			telemetry.RecordMessageQueueLatency(ctx, time.Since(msg.queuedAt).Seconds())
+			return nil
+		}
 
-	// If we got a 401, refresh the token and retry once
-	if errors.Is(err, errUnauthorized) {
-		a.Log.Info("Got 401 sending message, refreshing app token and retrying")
-		telemetry.AddSpanAttributes(span, attribute.Bool("token.refreshed_on_401", true))
-		telemetry.IncrementTokenRefreshOn401(ctx, "send_message")
-		if refreshErr := a.Secrets.RefreshAppTokenAndStore(); refreshErr != nil {
-			a.Log.Error("Failed to refresh app token after 401", refreshErr)
-			telemetry.RecordError(span, refreshErr)
-			telemetry.IncrementMessageSent(ctx, "error")
+		switch {
+		// If we got a 401, refresh the token and retry once
+		case errors.Is(err, errUnauthorized) && !refreshed:
+			a.Log.Info("Got 401 sending message, refreshing token and retrying")
+			telemetry.AddSpanAttributes(span, attribute.Bool("token.refreshed_on_401", true))
+			telemetry.IncrementTokenRefreshOn401(ctx, "send_message")
+			if refreshErr := a.refreshSenderToken(); refreshErr != nil {
+				a.Log.Error("Failed to refresh sender token after 401", refreshErr)
+				telemetry.RecordError(span, refreshErr)
+				telemetry.IncrementMessageSent(ctx, "error")
+				return err
+			}
+			refreshed = true
+		case errors.Is(err, errRetryable) && attempt < maxSendAttempts:
+			delay := retryDelay(attempt)
+			a.Log.Info(fmt.Sprintf("Retrying message to channel %s in %s (attempt %d): %v", msg.channelID, delay, attempt, err))
+			telemetry.IncrementMessageRetry(ctx)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			// A 429 also pauses the limiter until the Ratelimit-Reset time
+			if !a.limiter.wait(ctx) {
+				return ctx.Err()
+			}
+		case errors.Is(err, ErrMessageDropped):
+			telemetry.RecordError(span, err)
+			telemetry.IncrementMessageSent(ctx, "rejected")
 			return err
-		}
-		retryErr := a.sendMessageInternal(ctx, text)
-		if retryErr != nil {
-			telemetry.RecordError(span, retryErr)
+		default:
+			telemetry.RecordError(span, err)
 			telemetry.IncrementMessageSent(ctx, "error")
-		} else {
-			telemetry.IncrementMessageSent(ctx, "success")
+			return err
 		}
-		return retryErr
 	}
+}
 
-	telemetry.RecordError(span, err)
-	telemetry.IncrementMessageSent(ctx, "error")
-	return err
+// refreshSenderToken refreshes the token chat messages are sent with: the bot's user token or the app token
+func (a *Actions) refreshSenderToken() error {
+	if a.Config.Bot.Enabled() {
+		return a.Secrets.RefreshUserTokenAndStore(a.Config.Bot.ID)
+	}
+	return a.Secrets.RefreshAppTokenAndStore()
 }
 
-func (a *Actions) sendMessageInternal(ctx context.Context, text string) error {
+func (a *Actions) sendMessageInternal(ctx context.Context, msg outboundMessage) error {
+	// Messages are sent as the bot account when configured, otherwise each channel's broadcaster authorizes the bot to chat as them
 	message := subscriptions.ChatMessage{
-		BroadcasterID: userID,
-		SenderID:      userID,
-		Message:       text,
+		BroadcasterID:        msg.channelID,
+		SenderID:             a.Secrets.SenderID(msg.channelID),
+		Message:              msg.text,
+		ReplyParentMessageID: msg.replyTo,
 	}
 
 	payload, err := json.Marshal(message)
@@ -178,130 +306,90 @@ func (a *Actions) sendMessageInternal(ctx context.Context, text string) error {
 		a.Log.Error("Cannot send message - missing required credentials", headerErr)
 		return headerErr
 	}
+	token := headers.Token
+	if a.Config.Bot.Enabled() {
+		// The bot chats with its own user token, which carries the user:write:chat scope
+		if token, err = a.Secrets.GetUserToken(a.Config.Bot.ID); err != nil {
+			a.Log.Error("Cannot send message - bot user token missing from cache", err)
+			return err
+		}
+	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+headers.Token)
+	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Client-Id", headers.ClientID)
 
-	client := &http.Client{}
-	res, err := client.Do(req)
+	res, err := a.httpClient.Do(req)
 	if err != nil {
 		a.Log.Error("failed to send message", err)
-		return err
+		return fmt.Errorf("%w: %v", errRetryable, err)
 	}
 	defer res.Body.Close()
+	a.limiter.observe(res.Header)
 
 	if res.StatusCode == http.StatusUnauthorized {
 		a.Log.Info("Received 401 Unauthorized while sending message")
 		return errUnauthorized
 	}
 
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError {
+		a.Log.Info("Twitch could not take the message right now, response: " + strconv.Itoa(res.StatusCode))
+		return fmt.Errorf("%w: status code %d", errRetryable, res.StatusCode)
+	}
+
 	if res.StatusCode != http.StatusOK {
 		a.Log.Info("Unexpected status code while sending message, response: " + strconv.Itoa(res.StatusCode))
 		return fmt.Errorf("unexpected status code: %d", res.StatusCode)
 	}
 
+	var sent subscriptions.ChatMessageResponse
+	if err := json.NewDecoder(res.Body).Decode(&sent); err != nil {
+		a.Log.Error("Failed to decode send message response", err)
+		return err
+	}
+	for _, result := range sent.Data {
+		if result.IsSent {
+			a.rememberSent(msg.channelID, result.MessageID)
+			continue
+		}
+		reason := "unknown"
+		if result.DropReason != nil {
+			reason = fmt.Sprintf("%s: %s", result.DropReason.Code, result.DropReason.Message)
+		}
+		return fmt.Errorf("%w: %s", ErrMessageDropped, reason)
+	}
+
 	return nil
 }
 
-func (a *Actions) updateChannel(action subscriptions.ChatMessageEvent) {
-	ctx := context.Background()
-	_, span := telemetry.StartExternalSpan(ctx, "twitch.update_channel", "twitch", "update_channel")
-	defer span.End()
+// sentMessageKey marks a message the bot posted in a channel
+func sentMessageKey(channelID, messageID string) string {
+	return "chat:" + channelID + ":sent:" + messageID
+}
 
-	a.Log.Info("Changing the channel information")
-	// Check if user is me so I can update the channel
-	if action.Event.BroadcasterUserID != userID {
+// rememberSent records a message posted as the broadcaster, without a bot account chat cannot tell it apart
+// from the broadcaster typing
+func (a *Actions) rememberSent(channelID, messageID string) {
+	if a.Config.Bot.Enabled() || messageID == "" {
 		return
 	}
-
-	// Validate Twitch API credentials before attempting channel update
-	_, err := a.Secrets.BuildSecretHeaders()
-	if err != nil {
-		errMsg := fmt.Errorf("cannot update channel without valid API credentials: %w", err)
-		a.Log.Error("Cannot update channel - API credentials missing", errMsg)
-		telemetry.RecordError(span, errMsg)
-		return
+	if _, err := a.Cache.SetIfAbsent(sentMessageKey(channelID, messageID), sentMessageTTL); err != nil {
+		a.Log.Error("Failed to remember sent message "+messageID, err)
 	}
+}
 
-	// Build the new payload
-	splitMsg := strings.Split(action.Event.Message.Text, " ")
-	msg := strings.Join(splitMsg[1:], " ")
-	payloadBody := fmt.Sprintf(`{
-      "game_id":"%v",
-      "title":"🚨[Devops]🚨- %v",
-      "tags":["devops","Español","SpanishAndEnglish","coding","neovim","k8s","terraform","go","homelab", "nix", "gaming"],
-      "broadcaster_language":"es"}`,
-		softwareID, msg)
-	a.Log.Info("Today Command Ran")
-
-	const maxAttempts = 2
-	for attempt := 0; attempt < maxAttempts; attempt++ {
-		req, err := http.NewRequestWithContext(ctx, "PATCH", "https://api.twitch.tv/helix/channels?broadcaster_id="+userID, bytes.NewBuffer([]byte(payloadBody)))
-		if err != nil {
-			a.Log.Error("Could not form request to update channel info", err)
-			telemetry.RecordError(span, err)
-			return
-		}
-
-		// Validate headers exist before making API call
-		headers, err := a.Secrets.BuildSecretHeaders()
-		if err != nil {
-			headerErr := fmt.Errorf("failed to build required headers for updating channel: %w", err)
-			a.Log.Error("Cannot update channel - missing required credentials", headerErr)
-			telemetry.RecordError(span, headerErr)
-			return
-		}
-
-		userToken, err := a.Secrets.GetUserToken()
-		if err != nil {
-			headerErr := fmt.Errorf("failed to get user token from cache: %w", err)
-			a.Log.Error("Cannot update channel - user token missing from cache", headerErr)
-			telemetry.RecordError(span, headerErr)
-			return
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", "Bearer "+userToken)
-		req.Header.Set("Client-Id", headers.ClientID)
-
-		client := &http.Client{}
-		res, err := client.Do(req)
-		if err != nil {
-			a.Log.Error("Request could not be sent to update channel", err)
-			telemetry.RecordError(span, err)
-			return
-		}
-		_ = res.Body.Close()
-
-		telemetry.SetSpanStatus(span, res.StatusCode)
-
-		if res.StatusCode == http.StatusOK || res.StatusCode == http.StatusNoContent {
-			a.Log.Info("Channel updated successfully")
-			return
-		}
-
-		if res.StatusCode == http.StatusUnauthorized && attempt == 0 {
-			a.Log.Info("Got 401 updating channel, refreshing user token and retrying")
-			telemetry.AddSpanAttributes(span, attribute.Bool("token.refreshed_on_401", true))
-			telemetry.IncrementTokenRefreshOn401(ctx, "update_channel")
-			if refreshErr := a.Secrets.RefreshUserTokenAndStore(); refreshErr != nil {
-				a.Log.Error("Failed to refresh user token after 401", refreshErr)
-				telemetry.RecordError(span, refreshErr)
-				return
-			}
-			continue
-		}
-
-		if res.StatusCode == http.StatusBadRequest {
-			a.Log.Error("Received a bad request", errUpdateChannel)
-			telemetry.RecordError(span, errUpdateChannel)
-			return
-		}
-
-		unexpectedErr := fmt.Errorf("unexpected status: %d", res.StatusCode)
-		a.Log.Error("Unexpected status updating channel", unexpectedErr)
-		telemetry.RecordError(span, unexpectedErr)
-		return
+// sentByBot reports whether a chat message was posted by the bot, as the bot account or as the broadcaster
+func (a *Actions) sentByBot(msg subscriptions.ChatMessageEvent) bool {
+	if a.Config.Bot.Enabled() {
+		return msg.Event.ChatterUserID == a.Config.Bot.ID
+	}
+	if msg.Event.ChatterUserID != msg.Event.BroadcasterUserID {
+		return false
+	}
+	sent, err := a.Cache.Exists(sentMessageKey(msg.Event.BroadcasterUserID, msg.Event.MessageID))
+	if err != nil {
+		a.Log.Error("Failed to check whether message "+msg.Event.MessageID+" was sent by the bot", err)
+		return false
 	}
+	return sent
 }
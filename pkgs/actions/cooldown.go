@@ -0,0 +1,101 @@
+// Package actions handles Twitch chat commands and actions
+package actions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/locale"
+	"github.com/mvaldes14/twitch-bot/pkgs/telemetry"
+)
+
+// cooldownKey is the Redis marker of a running cooldown window, scope is "global" or a chatter ID
+func cooldownKey(channelID, command, scope string) string {
+	return fmt.Sprintf("cooldown:%s:%s:%s", channelID, command, scope)
+}
+
+// onCooldown reports whether a command must be suppressed and otherwise starts its cooldown windows.
+// Every window is checked before any is started, so a chatter held back by the global window keeps their
+// own window for when the command is available again. The per-user window is reported first so a chatter
+// spamming during the global window stays throttled. Redis errors let the command run rather than silencing chat.
+func (a *Actions) onCooldown(ctx context.Context, cmd *Command, inv Invocation) bool {
+	cooldown := a.Config.Cooldown(cmd.Name)
+	channelID := inv.ChannelID
+
+	windows := []cooldownWindow{
+		{"user", cooldownKey(channelID, cmd.Name, inv.ChatterID), cooldown.User},
+		{"global", cooldownKey(channelID, cmd.Name, "global"), cooldown.Global},
+	}
+	for _, w := range windows {
+		if w.window <= 0 {
+			continue
+		}
+		active, err := a.Cache.Exists(w.key)
+		if err != nil {
+			a.Log.Error("Failed to check cooldown of !"+cmd.Name, err)
+			continue
+		}
+		if active {
+			a.suppress(ctx, cmd, inv, w)
+			return true
+		}
+	}
+
+	// The global window is started first, it is the one concurrent chatters race for. When a later window is lost
+	// the command does not run, so the windows this call already started are cleared again
+	var startedKeys []string
+	for i := len(windows) - 1; i >= 0; i-- {
+		w := windows[i]
+		if w.window <= 0 {
+			continue
+		}
+		started, err := a.Cache.SetIfAbsent(w.key, w.window)
+		if err != nil {
+			a.Log.Error("Failed to start cooldown of !"+cmd.Name, err)
+			continue
+		}
+		if !started {
+			for _, key := range startedKeys {
+				if err := a.Cache.Delete(key); err != nil {
+					a.Log.Error("Failed to clear cooldown of !"+cmd.Name, err)
+				}
+			}
+			a.suppress(ctx, cmd, inv, w)
+			return true
+		}
+		startedKeys = append(startedKeys, w.key)
+	}
+	return false
+}
+
+// cooldownWindow is one of the cooldowns of a command, scope is "user" or "global"
+type cooldownWindow struct {
+	scope  string
+	key    string
+	window time.Duration
+}
+
+// suppress records a command held back by a cooldown window and tells chat when notices are enabled
+func (a *Actions) suppress(ctx context.Context, cmd *Command, inv Invocation, w cooldownWindow) {
+	a.Log.Info(fmt.Sprintf("[COMMAND: COOLDOWN] !%s from %s suppressed (%s)", cmd.Name, inv.ChatterName, w.scope))
+	telemetry.IncrementCommandSuppressed(ctx, cmd.Name, w.scope)
+	a.cooldownNotice(cmd, inv, w.window)
+}
+
+// cooldownNotice tells chat a command is on cooldown, at most once per window so the notice cannot be spammed either
+func (a *Actions) cooldownNotice(cmd *Command, inv Invocation, window time.Duration) {
+	if !a.Config.Cooldowns.Notice {
+		return
+	}
+	channelID := inv.ChannelID
+	first, err := a.Cache.SetIfAbsent(cooldownKey(channelID, cmd.Name, "notice"), window)
+	if err != nil || !first {
+		return
+	}
+	_ = a.Respond(inv, a.Catalog.Format(inv.Locale, locale.Cooldown,
+		"{user}", inv.ChatterName,
+		"{command}", a.Config.CommandPrefix+cmd.Name,
+		"{wait}", window.String(),
+	))
+}
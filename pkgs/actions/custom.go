@@ -0,0 +1,170 @@
+// Package actions handles Twitch chat commands and actions
+package actions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
+	"github.com/redis/go-redis/v9"
+)
+
+const maxResponseLength = 500
+
+var (
+	// ErrCommandNotFound is returned when a channel has no custom command with the given name
+	ErrCommandNotFound = errors.New("custom command not found")
+	// ErrCommandConflict is returned when a custom command would shadow an existing command
+	ErrCommandConflict = errors.New("command already exists")
+	// ErrInvalidCommand is returned when a custom command has an unusable name or response
+	ErrInvalidCommand = errors.New("invalid custom command")
+)
+
+// CustomCommand is a chat command added at runtime and stored in Redis
+type CustomCommand struct {
+	Name     string `json:"name"`
+	Response string `json:"response"`
+	// Permission is the lowest role allowed to run the command, see config.PermissionLevels
+	Permission string `json:"permission,omitempty"`
+	// Reply threads the response as a reply to the invoking message, true when not set
+	Reply     *bool     `json:"reply,omitempty"`
+	Count     int64     `json:"count"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// customCommandsKey is the Redis hash holding a channel's custom commands, one field per name
+func customCommandsKey(channelID string) string {
+	return "commands:" + channelID
+}
+
+// commandCountsKey is the Redis hash holding how often each command of a channel was used
+func commandCountsKey(channelID string) string {
+	return "commands:" + channelID + ":counts"
+}
+
+// ListCustomCommands returns the custom commands of a channel sorted by name
+func (a *Actions) ListCustomCommands(channelID string) ([]CustomCommand, error) {
+	fields, err := a.Cache.GetFields(customCommandsKey(channelID))
+	if err != nil {
+		return nil, err
+	}
+	counts, err := a.Cache.GetFields(commandCountsKey(channelID))
+	if err != nil {
+		return nil, err
+	}
+
+	commands := make([]CustomCommand, 0, len(fields))
+	for name, value := range fields {
+		var cmd CustomCommand
+		if err := json.Unmarshal([]byte(value), &cmd); err != nil {
+			a.Log.Error("Skipping unreadable custom command "+name, err)
+			continue
+		}
+		cmd.Count, _ = strconv.ParseInt(counts[name], 10, 64)
+		commands = append(commands, cmd)
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+	return commands, nil
+}
+
+// GetCustomCommand returns a single custom command of a channel
+func (a *Actions) GetCustomCommand(channelID, name string) (CustomCommand, error) {
+	var cmd CustomCommand
+	name = a.customCommandName(name)
+	value, err := a.Cache.GetField(customCommandsKey(channelID), name)
+	if errors.Is(err, redis.Nil) {
+		return cmd, ErrCommandNotFound
+	}
+	if err != nil {
+		return cmd, err
+	}
+	if err := json.Unmarshal([]byte(value), &cmd); err != nil {
+		return cmd, err
+	}
+	if count, err := a.Cache.GetField(commandCountsKey(channelID), name); err == nil {
+		cmd.Count, _ = strconv.ParseInt(count, 10, 64)
+	}
+	return cmd, nil
+}
+
+// CreateCustomCommand adds a custom command, failing if the name is taken by any command of the channel
+func (a *Actions) CreateCustomCommand(channelID string, cmd CustomCommand) (CustomCommand, error) {
+	if err := a.validateCustomCommand(channelID, &cmd); err != nil {
+		return cmd, err
+	}
+	if _, err := a.GetCustomCommand(channelID, cmd.Name); err == nil {
+		return cmd, fmt.Errorf("%w: !%s", ErrCommandConflict, cmd.Name)
+	} else if !errors.Is(err, ErrCommandNotFound) {
+		return cmd, err
+	}
+	return cmd, a.storeCustomCommand(channelID, &cmd)
+}
+
+// UpdateCustomCommand replaces the response of an existing custom command
+func (a *Actions) UpdateCustomCommand(channelID string, cmd CustomCommand) (CustomCommand, error) {
+	if err := a.validateCustomCommand(channelID, &cmd); err != nil {
+		return cmd, err
+	}
+	existing, err := a.GetCustomCommand(channelID, cmd.Name)
+	if err != nil {
+		return cmd, err
+	}
+	cmd.Count = existing.Count
+	return cmd, a.storeCustomCommand(channelID, &cmd)
+}
+
+// DeleteCustomCommand removes a custom command and its usage count
+func (a *Actions) DeleteCustomCommand(channelID, name string) error {
+	name = a.customCommandName(name)
+	removed, err := a.Cache.DeleteField(customCommandsKey(channelID), name)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrCommandNotFound
+	}
+	if _, err := a.Cache.DeleteField(commandCountsKey(channelID), name); err != nil {
+		a.Log.Error("Failed to reset usage count of deleted command "+name, err)
+	}
+	return nil
+}
+
+func (a *Actions) storeCustomCommand(channelID string, cmd *CustomCommand) error {
+	cmd.UpdatedAt = time.Now().UTC()
+	value, err := json.Marshal(CustomCommand{Name: cmd.Name, Response: cmd.Response, Permission: cmd.Permission, Reply: cmd.Reply, UpdatedAt: cmd.UpdatedAt})
+	if err != nil {
+		return err
+	}
+	return a.Cache.SetField(customCommandsKey(channelID), cmd.Name, string(value))
+}
+
+// validateCustomCommand normalizes the name and rejects commands that would shadow built-in or configured ones
+func (a *Actions) validateCustomCommand(channelID string, cmd *CustomCommand) error {
+	cmd.Name = a.customCommandName(cmd.Name)
+	cmd.Response = strings.TrimSpace(cmd.Response)
+	if !config.ValidCommandName(cmd.Name) {
+		return fmt.Errorf("%w: name %q must be a single word", ErrInvalidCommand, cmd.Name)
+	}
+	if cmd.Response == "" || len(cmd.Response) > maxResponseLength {
+		return fmt.Errorf("%w: response must be 1-%d characters", ErrInvalidCommand, maxResponseLength)
+	}
+	if _, err := ParsePermission(cmd.Permission); err != nil {
+		return err
+	}
+	if registry, ok := a.Registries[channelID]; ok {
+		if _, exists := registry.Lookup(cmd.Name); exists {
+			return fmt.Errorf("%w: !%s is a built-in or configured command", ErrCommandConflict, cmd.Name)
+		}
+	}
+	return nil
+}
+
+// customCommandName is the stored form of a command name: trimmed, without the prefix and lower-cased
+func (a *Actions) customCommandName(name string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), a.Config.CommandPrefix))
+}
@@ -0,0 +1,114 @@
+// Package actions handles Twitch chat commands and actions
+package actions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const helixEndpoint = "https://api.twitch.tv/helix"
+
+// HelixError is a Helix response outside the 2xx range, Message is the reason given by Twitch
+type HelixError struct {
+	Status  int
+	Message string
+}
+
+func (e *HelixError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("helix status %d", e.Status)
+	}
+	return fmt.Sprintf("helix status %d: %s", e.Status, e.Message)
+}
+
+// helixUserRequest calls a Helix endpoint authorized with the user token of userID, e.g. the broadcaster
+// or the bot acting as moderator. A 401 refreshes the token and retries once.
+// payload is sent as JSON when not nil and a 2xx body is decoded into target when not nil
+func (a *Actions) helixUserRequest(ctx context.Context, operation, method, path, userID string, payload, target any) error {
+	ctx, span := telemetry.StartExternalSpan(ctx, "twitch."+operation, "twitch", operation)
+	defer span.End()
+	telemetry.AddSpanAttributes(span, attribute.String("twitch.user_id", userID))
+
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			telemetry.RecordError(span, err)
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		headers, err := a.Secrets.BuildSecretHeaders()
+		if err != nil {
+			headerErr := fmt.Errorf("failed to build required headers for %s: %w", operation, err)
+			telemetry.RecordError(span, headerErr)
+			return headerErr
+		}
+		userToken, err := a.Secrets.GetUserToken(userID)
+		if err != nil {
+			telemetry.RecordError(span, err)
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, helixEndpoint+path, bytes.NewReader(body))
+		if err != nil {
+			telemetry.RecordError(span, err)
+			return err
+		}
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Authorization", "Bearer "+userToken)
+		req.Header.Set("Client-Id", headers.ClientID)
+
+		res, err := a.httpClient.Do(req)
+		if err != nil {
+			telemetry.RecordError(span, err)
+			return err
+		}
+		respBody, err := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if err != nil {
+			telemetry.RecordError(span, err)
+			return err
+		}
+		telemetry.SetSpanStatus(span, res.StatusCode)
+
+		if res.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			a.Log.Info(fmt.Sprintf("Got 401 on %s, refreshing user token and retrying", operation))
+			telemetry.AddSpanAttributes(span, attribute.Bool("token.refreshed_on_401", true))
+			telemetry.IncrementTokenRefreshOn401(ctx, operation)
+			if refreshErr := a.Secrets.RefreshUserTokenAndStore(userID); refreshErr != nil {
+				telemetry.RecordError(span, refreshErr)
+				return refreshErr
+			}
+			continue
+		}
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			helixErr := &HelixError{Status: res.StatusCode}
+			var reason struct {
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(respBody, &reason) == nil {
+				helixErr.Message = reason.Message
+			}
+			telemetry.RecordError(span, helixErr)
+			return helixErr
+		}
+		if target != nil && len(respBody) > 0 {
+			if err := json.Unmarshal(respBody, target); err != nil {
+				telemetry.RecordError(span, err)
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,152 @@
+// Package actions handles Twitch chat commands and actions
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/subscriptions"
+)
+
+var (
+	// ErrUsage is returned by handlers called with missing or malformed arguments, the usage is sent to chat
+	ErrUsage             = errors.New("invalid command usage")
+	errUnterminatedQuote = errors.New("unterminated quote")
+)
+
+// Invocation is a parsed chat command as seen by its handler
+type Invocation struct {
+	// Name is the command or alias as typed, lower cased and without the prefix
+	Name string
+	// Args are the arguments split on spaces, quoted text is kept as one argument and @ is removed from mentions
+	Args []string
+	// RawArgs is the text after the command name as typed
+	RawArgs string
+	// Mentions are the users mentioned with @, in order
+	Mentions     []string
+	ChannelID    string
+	ChatterID    string
+	ChatterLogin string
+	ChatterName  string
+	// Badges are the badge set IDs of the chatter, e.g. "moderator" or "subscriber"
+	Badges     []string
+	Permission Permission
+	// MessageID is the chat message that invoked the command, Respond replies to it when Reply is set
+	MessageID string
+	Reply     bool
+	// Locale is the catalog locale the command answers in
+	Locale string
+}
+
+// Respond answers an invocation in its channel, threaded as a reply unless the command disabled replies
+func (a *Actions) Respond(inv Invocation, text string) error {
+	if !inv.Reply {
+		return a.SendMessage(inv.ChannelID, text)
+	}
+	return a.Reply(inv.ChannelID, inv.MessageID, text)
+}
+
+// Arg returns the argument at index i, empty when there are fewer arguments
+func (inv Invocation) Arg(i int) string {
+	if i < 0 || i >= len(inv.Args) {
+		return ""
+	}
+	return inv.Args[i]
+}
+
+// usageError wraps ErrUsage with the reason shown in chat
+func usageError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrUsage, fmt.Sprintf(format, args...))
+}
+
+// parseInvocation splits a chat message into an invocation, ok is false when the message is not a command.
+// Malformed arguments such as an unterminated quote are returned as a usage error.
+func parseInvocation(prefix string, msg subscriptions.ChatMessageEvent) (inv Invocation, ok bool, err error) {
+	text := strings.TrimSpace(msg.Event.Message.Text)
+	if prefix == "" || !strings.HasPrefix(text, prefix) {
+		return inv, false, nil
+	}
+	// The name ends at any whitespace, chat clients may send a tab or a non-breaking space after it
+	name, rawArgs := strings.TrimPrefix(text, prefix), ""
+	if i := strings.IndexFunc(name, unicode.IsSpace); i >= 0 {
+		name, rawArgs = name[:i], name[i:]
+	}
+	if name == "" {
+		return inv, false, nil
+	}
+
+	inv = Invocation{
+		Name:         strings.ToLower(name),
+		RawArgs:      strings.TrimSpace(rawArgs),
+		ChannelID:    msg.Event.BroadcasterUserID,
+		ChatterID:    msg.Event.ChatterUserID,
+		ChatterLogin: msg.Event.ChatterUserLogin,
+		ChatterName:  msg.Event.ChatterUserName,
+		Permission:   chatterPermission(msg),
+		MessageID:    msg.Event.MessageID,
+		Reply:        true,
+	}
+	for _, badge := range msg.Event.Badges {
+		inv.Badges = append(inv.Badges, badge.SetID)
+	}
+
+	tokens, err := tokenize(inv.RawArgs)
+	if err != nil {
+		return inv, true, usageError("%v", err)
+	}
+	for _, token := range tokens {
+		if mention, isMention := strings.CutPrefix(token, "@"); isMention && mention != "" {
+			inv.Mentions = append(inv.Mentions, mention)
+			token = mention
+		}
+		inv.Args = append(inv.Args, token)
+	}
+	return inv, true, nil
+}
+
+// tokenize splits text on whitespace, keeping text in single or double quotes together.
+// A quote only opens at the start of an argument, so apostrophes as in "can't" are kept as text.
+// A backslash escapes the next character inside quotes.
+func tokenize(text string) ([]string, error) {
+	var (
+		tokens  []string
+		current strings.Builder
+		quote   rune
+		escaped bool
+		inToken bool
+	)
+	for _, r := range text {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote != 0 && r == '\\':
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case !inToken && (r == '"' || r == '\''):
+			quote = r
+			inToken = true
+		case unicode.IsSpace(r):
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+	if quote != 0 {
+		return nil, errUnterminatedQuote
+	}
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
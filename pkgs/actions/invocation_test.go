@@ -0,0 +1,150 @@
+package actions
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/subscriptions"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []string
+		wantErr error
+	}{
+		{name: "empty", text: "", want: nil},
+		{name: "words", text: "  hello   world ", want: []string{"hello", "world"}},
+		{name: "double quotes", text: `"Best game?" yes`, want: []string{"Best game?", "yes"}},
+		{name: "single quotes", text: `'hello world' again`, want: []string{"hello world", "again"}},
+		{name: "apostrophe", text: "Let's code", want: []string{"Let's", "code"}},
+		{name: "apostrophes in several words", text: "I'm back, don't leave", want: []string{"I'm", "back,", "don't", "leave"}},
+		{name: "apostrophe after quoted title", text: `"Q" yes | can't`, want: []string{"Q", "yes", "|", "can't"}},
+		{name: "quote inside word", text: `say"hi"`, want: []string{`say"hi"`}},
+		{name: "escaped quote", text: `"say \"hi\"" now`, want: []string{`say "hi"`, "now"}},
+		{name: "apostrophe inside double quotes", text: `"it's live"`, want: []string{"it's live"}},
+		{name: "empty quotes", text: `"" x`, want: []string{"", "x"}},
+		{name: "unterminated quote", text: `"open ended`, wantErr: errUnterminatedQuote},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tokenize(tt.text)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("tokenize(%q) error = %v, want %v", tt.text, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+// chatMessage builds a chat message event the way Twitch delivers it
+func chatMessage(t *testing.T, text string, badges ...string) subscriptions.ChatMessageEvent {
+	t.Helper()
+	var event struct {
+		Event map[string]any `json:"event"`
+	}
+	event.Event = map[string]any{
+		"broadcaster_user_id": "100",
+		"chatter_user_id":     "200",
+		"chatter_user_login":  "viewer",
+		"chatter_user_name":   "Viewer",
+		"message_id":          "msg-1",
+		"message":             map[string]string{"text": text},
+	}
+	var badgeList []map[string]string
+	for _, badge := range badges {
+		badgeList = append(badgeList, map[string]string{"set_id": badge})
+	}
+	event.Event["badges"] = badgeList
+	body, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg subscriptions.ChatMessageEvent
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestParseInvocation(t *testing.T) {
+	tests := []struct {
+		name         string
+		prefix       string
+		text         string
+		wantOK       bool
+		wantName     string
+		wantArgs     []string
+		wantRaw      string
+		wantMentions []string
+		wantUsage    bool
+	}{
+		{name: "not a command", prefix: "!", text: "hello chat"},
+		{name: "prefix only", prefix: "!", text: "!"},
+		{name: "no prefix configured", prefix: "", text: "!song"},
+		{name: "no arguments", prefix: "!", text: "!song", wantOK: true, wantName: "song"},
+		{name: "name is lower cased", prefix: "!", text: "  !SoNg  ", wantOK: true, wantName: "song"},
+		{name: "multi character prefix", prefix: "?!", text: "?!Uptime", wantOK: true, wantName: "uptime"},
+		{name: "other prefix", prefix: "?", text: "!song"},
+		{name: "tab after name", prefix: "!", text: "!so\t@friend", wantOK: true, wantName: "so", wantRaw: "@friend", wantArgs: []string{"friend"}, wantMentions: []string{"friend"}},
+		{name: "non-breaking space after name", prefix: "!", text: "!title\u00a0Let's code", wantOK: true, wantName: "title", wantRaw: "Let's code", wantArgs: []string{"Let's", "code"}},
+		{
+			name: "mentions", prefix: "!", text: "!so @Streamer @ friend",
+			wantOK: true, wantName: "so", wantRaw: "@Streamer @ friend",
+			wantArgs: []string{"Streamer", "@", "friend"}, wantMentions: []string{"Streamer"},
+		},
+		{
+			name: "apostrophe in title", prefix: "!", text: "!title Let's code",
+			wantOK: true, wantName: "title", wantRaw: "Let's code", wantArgs: []string{"Let's", "code"},
+		},
+		{
+			name: "poll with apostrophe choice", prefix: "!", text: `!poll "Ship it?" yes | can't`,
+			wantOK: true, wantName: "poll", wantRaw: `"Ship it?" yes | can't`,
+			wantArgs: []string{"Ship it?", "yes", "|", "can't"},
+		},
+		{
+			name: "unterminated quote", prefix: "!", text: `!poll "Ship it? yes`,
+			wantOK: true, wantName: "poll", wantRaw: `"Ship it? yes`, wantUsage: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv, ok, err := parseInvocation(tt.prefix, chatMessage(t, tt.text, "moderator", "subscriber"))
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if errors.Is(err, ErrUsage) != tt.wantUsage {
+				t.Fatalf("err = %v, want usage error %v", err, tt.wantUsage)
+			}
+			if !ok {
+				return
+			}
+			if inv.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", inv.Name, tt.wantName)
+			}
+			if inv.RawArgs != tt.wantRaw {
+				t.Errorf("RawArgs = %q, want %q", inv.RawArgs, tt.wantRaw)
+			}
+			if tt.wantUsage {
+				return
+			}
+			if !reflect.DeepEqual(inv.Args, tt.wantArgs) {
+				t.Errorf("Args = %q, want %q", inv.Args, tt.wantArgs)
+			}
+			if !reflect.DeepEqual(inv.Mentions, tt.wantMentions) {
+				t.Errorf("Mentions = %q, want %q", inv.Mentions, tt.wantMentions)
+			}
+			if inv.ChannelID != "100" || inv.ChatterID != "200" || inv.MessageID != "msg-1" || !inv.Reply {
+				t.Errorf("invocation fields not copied from the message: %+v", inv)
+			}
+			if !reflect.DeepEqual(inv.Badges, []string{"moderator", "subscriber"}) {
+				t.Errorf("Badges = %q", inv.Badges)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Package actions handles Twitch chat commands and actions
+package actions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/locale"
+)
+
+// sessionLocales remembers the locale picked with !lang for each channel until the stream ends
+type sessionLocales struct {
+	mu     sync.Mutex
+	active map[string]string
+}
+
+// Locale returns the active locale of a channel: the one picked with !lang,
+// then the channel's configured locale, then locale.default
+func (a *Actions) Locale(channelID string) string {
+	a.locales.mu.Lock()
+	active, ok := a.locales.active[channelID]
+	a.locales.mu.Unlock()
+	if ok {
+		return active
+	}
+	if channel, ok := a.Config.Channel(channelID); ok && channel.Locale != "" {
+		return locale.Normalize(channel.Locale)
+	}
+	return locale.Normalize(a.Config.Locale.Default)
+}
+
+// SetLocale switches the active locale of a channel for the rest of the stream
+func (a *Actions) SetLocale(channelID, loc string) error {
+	if !a.Catalog.Supported(loc) {
+		return usageError("%s", a.Catalog.Format(a.Locale(channelID), locale.LangUnknown, "{locale}", loc))
+	}
+	a.locales.mu.Lock()
+	defer a.locales.mu.Unlock()
+	a.locales.active[channelID] = locale.Normalize(loc)
+	return nil
+}
+
+// resetLocale drops the locale picked with !lang so the next stream starts with the configured one
+func (a *Actions) resetLocale(channelID string) {
+	a.locales.mu.Lock()
+	defer a.locales.mu.Unlock()
+	delete(a.locales.active, channelID)
+}
+
+// Translate renders a catalog message in the active locale of a channel, see locale.Catalog.Format
+func (a *Actions) Translate(channelID, key string, vars ...string) string {
+	return a.Catalog.Format(a.Locale(channelID), key, vars...)
+}
+
+// commandLocale is the locale a command answers in, the one it is pinned to in locale.commands or the active one
+func (a *Actions) commandLocale(name, channelID string) string {
+	if loc := a.Config.CommandLocale(name); loc != "" {
+		return locale.Normalize(loc)
+	}
+	return a.Locale(channelID)
+}
+
+// switchLocale shows the active locale of the channel or switches it to the one given
+func (a *Actions) switchLocale(_ context.Context, inv Invocation) error {
+	if len(inv.Args) == 0 {
+		return a.Respond(inv, a.Catalog.Format(inv.Locale, locale.LangCurrent,
+			"{locale}", a.Locale(inv.ChannelID),
+			"{locales}", strings.Join(a.Catalog.Locales(), ", "),
+		))
+	}
+	if err := a.SetLocale(inv.ChannelID, inv.Arg(0)); err != nil {
+		return err
+	}
+	active := a.Locale(inv.ChannelID)
+	a.Log.Info(fmt.Sprintf("[LOCALE: %s] Set for channel %s by %s", active, inv.ChannelID, inv.ChatterName))
+	return a.Respond(inv, a.Catalog.Format(active, locale.LangChanged, "{locale}", active))
+}
@@ -0,0 +1,400 @@
+// Package actions handles Twitch chat commands and actions
+package actions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
+	"github.com/mvaldes14/twitch-bot/pkgs/locale"
+	"github.com/mvaldes14/twitch-bot/pkgs/subscriptions"
+	"github.com/mvaldes14/twitch-bot/pkgs/telemetry"
+)
+
+// Moderation rules, used as labels in logs, metrics and the audit log
+const (
+	RuleBlocklist = "blocklist"
+	RuleLinks     = "links"
+	RuleCaps      = "caps"
+	RuleEmotes    = "emotes"
+	RuleRepeats   = "repeats"
+)
+
+// auditLogSize is how many moderation actions are kept per channel
+const auditLogSize = 500
+
+// noticeWindow is how often chat is told about removed messages per rule, a spam wave gets one notice
+const noticeWindow = 30 * time.Second
+
+// linkPattern finds URLs and bare domains such as example.com/path. The groups are the scheme or "www.",
+// the host and its top level domain
+var linkPattern = regexp.MustCompile(`(?i)\b(https?://|www\.)?((?:[a-z0-9-]+\.)+([a-z]{2,}))\b`)
+
+// linkTLDs are the top level domains a bare domain must end in to count as a link, so words joined by a
+// missing space such as "stream.thanks" or "ok.so" are not taken for links. URLs with a scheme or "www."
+// are links whatever their domain
+var linkTLDs = map[string]bool{
+	"com": true, "net": true, "org": true, "info": true, "biz": true, "io": true, "gg": true, "tv": true,
+	"co": true, "ly": true, "be": true, "dev": true, "app": true, "xyz": true, "site": true, "online": true,
+	"live": true, "link": true, "club": true, "top": true, "shop": true, "store": true, "ru": true, "cn": true,
+	"de": true, "uk": true, "fr": true, "es": true, "mx": true, "ar": true, "br": true, "cl": true, "us": true,
+	"ca": true, "eu": true, "gl": true, "ws": true, "cc": true, "pw": true, "tk": true,
+}
+
+// ruleNotices are the catalog messages telling chat why a message was removed
+var ruleNotices = map[string]string{
+	RuleBlocklist: locale.ModerationBlocklist,
+	RuleLinks:     locale.ModerationLinks,
+	RuleCaps:      locale.ModerationCaps,
+	RuleEmotes:    locale.ModerationEmotes,
+	RuleRepeats:   locale.ModerationRepeats,
+}
+
+// AuditEntry is a moderation action taken by the bot, automatically or for a moderator's command
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	UserID    string    `json:"user_id"`
+	UserLogin string    `json:"user_login"`
+	MessageID string    `json:"message_id,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Rule      string    `json:"rule"`
+	Action    string    `json:"action"`
+	Duration  string    `json:"duration,omitempty"`
+	// Strike is the chatter's number of violations within the strike window, zero for commands
+	Strike int64 `json:"strike,omitempty"`
+	// Moderator is the login of the moderator who ran the command, empty for automatic actions
+	Moderator string `json:"moderator,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	// Error is set when Twitch refused the action
+	Error string `json:"error,omitempty"`
+}
+
+// auditKey is the Redis list of a channel's moderation actions, newest first
+func auditKey(channelID string) string {
+	return "moderation:" + channelID + ":audit"
+}
+
+// strikesKey counts a chatter's violations within the strike window
+func strikesKey(channelID, userID string) string {
+	return "moderation:" + channelID + ":strikes:" + userID
+}
+
+// permitKey marks a chatter allowed to post links by !permit
+func permitKey(channelID, login string) string {
+	return "moderation:" + channelID + ":permit:" + strings.ToLower(login)
+}
+
+// noticeKey marks a running notice window of a rule
+func noticeKey(channelID, rule string) string {
+	return "moderation:" + channelID + ":notice:" + rule
+}
+
+// repeatKey counts how often a chatter sent the same text within the repeat window
+func repeatKey(channelID, userID, text string) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	sum := sha256.Sum256([]byte(normalized))
+	return "moderation:" + channelID + ":repeat:" + userID + ":" + hex.EncodeToString(sum[:8])
+}
+
+// compileBlocklist compiles the blocklist patterns case insensitively, skipping invalid ones
+func (a *Actions) compileBlocklist() []*regexp.Regexp {
+	var patterns []*regexp.Regexp
+	for _, pattern := range a.Config.Moderation.Blocklist.Patterns {
+		compiled, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			a.Log.Error("Skipping invalid blocklist pattern "+pattern, err)
+			continue
+		}
+		patterns = append(patterns, compiled)
+	}
+	return patterns
+}
+
+// Moderate runs the moderation rules on a chat message before it is parsed as a command.
+// It returns true when the message broke a rule and was acted on, in which case no command runs
+func (a *Actions) Moderate(msg subscriptions.ChatMessageEvent) bool {
+	rules := a.Config.Moderation
+	if !rules.Enabled {
+		return false
+	}
+	if a.Config.Bot.Enabled() && msg.Event.ChatterUserID == a.Config.Bot.ID {
+		return false
+	}
+	permission := chatterPermission(msg)
+	if exempt, err := ParsePermission(rules.Exempt); err == nil && permission >= exempt {
+		return false
+	}
+
+	rule, step := a.brokenRule(msg, permission)
+	if rule == "" {
+		return false
+	}
+	a.enforce(context.Background(), msg, rule, step)
+	return true
+}
+
+// brokenRule returns the first rule a message breaks and the action configured for it, empty when it breaks none
+func (a *Actions) brokenRule(msg subscriptions.ChatMessageEvent, permission Permission) (string, config.ModerationStep) {
+	rules := a.Config.Moderation
+	channelID := msg.Event.BroadcasterUserID
+	text := msg.Event.Message.Text
+
+	if a.blocked(text) {
+		return RuleBlocklist, rules.Blocklist.Action
+	}
+	if rules.Links.Enabled {
+		if allowed, err := ParsePermission(rules.Links.Permission); err == nil && permission < allowed && a.hasForbiddenLink(text) {
+			permitted, err := a.Cache.Exists(permitKey(channelID, msg.Event.ChatterUserLogin))
+			if err != nil {
+				a.Log.Error("Failed to check link permit of "+msg.Event.ChatterUserLogin, err)
+			}
+			// Redis errors let the message through rather than punishing a permitted chatter
+			if !permitted && err == nil {
+				return RuleLinks, rules.Links.Action
+			}
+		}
+	}
+
+	words, emotes := messageWords(msg)
+	if rules.Caps.MinLength > 0 && shouting(words, rules.Caps.MinLength, rules.Caps.MaxRatio) {
+		return RuleCaps, rules.Caps.Action
+	}
+	if rules.Emotes.Max > 0 && emotes > rules.Emotes.Max {
+		return RuleEmotes, rules.Emotes.Action
+	}
+	if rules.Repeats.Count > 0 {
+		count, err := a.Cache.IncrementWithExpiry(repeatKey(channelID, msg.Event.ChatterUserID, text), rules.Repeats.Window)
+		if err != nil {
+			a.Log.Error("Failed to count repeated message", err)
+		} else if count >= int64(rules.Repeats.Count) {
+			return RuleRepeats, rules.Repeats.Action
+		}
+	}
+	return "", config.ModerationStep{}
+}
+
+// blocked reports whether text contains a blocklisted phrase or matches a blocklist pattern
+func (a *Actions) blocked(text string) bool {
+	lower := strings.ToLower(text)
+	for _, phrase := range a.Config.Moderation.Blocklist.Phrases {
+		if phrase != "" && strings.Contains(lower, strings.ToLower(phrase)) {
+			return true
+		}
+	}
+	for _, pattern := range a.blocklist {
+		if pattern.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasForbiddenLink reports whether text links to a domain outside the allowed domains
+func (a *Actions) hasForbiddenLink(text string) bool {
+	for _, match := range linkPattern.FindAllStringSubmatch(text, -1) {
+		if match[1] == "" && !linkTLDs[strings.ToLower(match[3])] {
+			continue
+		}
+		host := strings.ToLower(match[2])
+		allowed := false
+		for _, domain := range a.Config.Moderation.Links.AllowedDomains {
+			domain = strings.ToLower(domain)
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// messageWords returns the text of a message without its emotes, and the number of emotes
+func messageWords(msg subscriptions.ChatMessageEvent) (string, int) {
+	fragments := msg.Event.Message.Fragments
+	if len(fragments) == 0 {
+		return msg.Event.Message.Text, 0
+	}
+	var (
+		words  strings.Builder
+		emotes int
+	)
+	for _, fragment := range fragments {
+		if fragment.Type == "emote" {
+			emotes++
+			continue
+		}
+		words.WriteString(fragment.Text)
+	}
+	return words.String(), emotes
+}
+
+// shouting reports whether text has at least minLength letters and more than maxRatio of them upper case
+func shouting(text string, minLength int, maxRatio float64) bool {
+	letters, upper := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+	return letters >= minLength && float64(upper)/float64(letters) > maxRatio
+}
+
+// enforce counts a strike for the chatter, applies the rule's action or the escalation step for the strike,
+// records it in the audit log and optionally tells chat why
+func (a *Actions) enforce(ctx context.Context, msg subscriptions.ChatMessageEvent, rule string, step config.ModerationStep) {
+	rules := a.Config.Moderation
+	event := msg.Event
+	channelID := event.BroadcasterUserID
+
+	strike, err := a.Cache.IncrementWithExpiry(strikesKey(channelID, event.ChatterUserID), rules.StrikeWindow)
+	if err != nil {
+		a.Log.Error("Failed to count moderation strike for "+event.ChatterUserLogin, err)
+		strike = 1
+	}
+	if step.Action == "" {
+		step = rules.Escalation[min(int(strike), len(rules.Escalation))-1]
+	}
+
+	entry := AuditEntry{
+		Time:      time.Now(),
+		UserID:    event.ChatterUserID,
+		UserLogin: event.ChatterUserLogin,
+		MessageID: event.MessageID,
+		Message:   event.Message.Text,
+		Rule:      rule,
+		Action:    step.Action,
+		Strike:    strike,
+	}
+	reason := "Automatic moderation: " + rule
+	switch step.Action {
+	case config.ModerationDelete:
+		err = a.deleteChatMessage(ctx, channelID, event.MessageID)
+	case config.ModerationTimeout:
+		entry.Duration = step.Duration.String()
+		err = a.banUser(ctx, channelID, event.ChatterUserID, step.Duration, reason)
+	case config.ModerationBan:
+		err = a.banUser(ctx, channelID, event.ChatterUserID, 0, reason)
+	}
+
+	result := "success"
+	if err != nil {
+		result = "error"
+		entry.Error = err.Error()
+		a.Log.Error(fmt.Sprintf("Failed to %s message from %s", step.Action, event.ChatterUserLogin), err)
+	}
+	a.Log.Info(fmt.Sprintf("[MODERATION: %s] %s %s (strike %d) in channel %s", strings.ToUpper(rule), step.Action, event.ChatterUserLogin, strike, channelID))
+	telemetry.IncrementModerationAction(ctx, rule, step.Action, result)
+	a.recordAudit(channelID, entry)
+
+	if err == nil && rules.Notice {
+		a.moderationNotice(channelID, rule, event.ChatterUserName)
+	}
+}
+
+// moderationNotice tells chat why a message was removed, at most once per rule within noticeWindow
+func (a *Actions) moderationNotice(channelID, rule, chatter string) {
+	first, err := a.Cache.SetIfAbsent(noticeKey(channelID, rule), noticeWindow)
+	if err != nil || !first {
+		return
+	}
+	_ = a.SendMessage(channelID, a.Translate(channelID, ruleNotices[rule],
+		"{user}", chatter,
+		"{permit}", a.Config.CommandPrefix+"permit",
+	))
+}
+
+// recordAudit stores a moderation action in the channel's audit log
+func (a *Actions) recordAudit(channelID string, entry AuditEntry) {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		a.Log.Error("Failed to marshal moderation audit entry", err)
+		return
+	}
+	if err := a.Cache.PushCapped(auditKey(channelID), string(value), auditLogSize); err != nil {
+		a.Log.Error("Failed to store moderation audit entry", err)
+	}
+}
+
+// ModerationAudit returns up to limit of the latest moderation actions in a channel, newest first.
+// A limit outside 1-500 returns the whole audit log
+func (a *Actions) ModerationAudit(channelID string, limit int) ([]AuditEntry, error) {
+	if limit <= 0 || limit > auditLogSize {
+		limit = auditLogSize
+	}
+	values, err := a.Cache.GetList(auditKey(channelID), int64(limit))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]AuditEntry, 0, len(values))
+	for _, value := range values {
+		var entry AuditEntry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			a.Log.Error("Skipping unreadable moderation audit entry", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// deleteChatMessage removes a chat message, as the bot account or the broadcaster
+func (a *Actions) deleteChatMessage(ctx context.Context, channelID, messageID string) error {
+	moderatorID := a.Secrets.SenderID(channelID)
+	query := url.Values{
+		"broadcaster_id": {channelID},
+		"moderator_id":   {moderatorID},
+		"message_id":     {messageID},
+	}
+	return a.helixUserRequest(ctx, "delete_chat_message", http.MethodDelete, "/moderation/chat?"+query.Encode(), moderatorID, nil, nil)
+}
+
+// banUser bans a chatter, or times them out when duration is positive
+func (a *Actions) banUser(ctx context.Context, channelID, userID string, duration time.Duration, reason string) error {
+	moderatorID := a.Secrets.SenderID(channelID)
+	query := url.Values{
+		"broadcaster_id": {channelID},
+		"moderator_id":   {moderatorID},
+	}
+	type banData struct {
+		UserID   string `json:"user_id"`
+		Duration int    `json:"duration,omitempty"`
+		Reason   string `json:"reason,omitempty"`
+	}
+	payload := struct {
+		Data banData `json:"data"`
+	}{
+		Data: banData{UserID: userID, Duration: int(duration.Seconds()), Reason: reason},
+	}
+	return a.helixUserRequest(ctx, "ban_user", http.MethodPost, "/moderation/bans?"+query.Encode(), moderatorID, payload, nil)
+}
+
+// permitLinks lets a chatter post links for the configured permit duration, starting over when permitted again
+func (a *Actions) permitLinks(_ context.Context, inv Invocation) error {
+	login := strings.ToLower(inv.Arg(0))
+	window := a.Config.Moderation.Links.PermitDuration
+	// Permitting again restarts the window, so the chatter always gets the duration the reply announces
+	if err := a.Cache.SetMarker(permitKey(inv.ChannelID, login), window); err != nil {
+		return err
+	}
+	a.Log.Info(fmt.Sprintf("[MODERATION: PERMIT] %s may post links for %s in channel %s", login, window, inv.ChannelID))
+	return a.Respond(inv, a.Catalog.Format(inv.Locale, locale.Permit,
+		"{user}", inv.Arg(0),
+		"{duration}", window.String(),
+	))
+}
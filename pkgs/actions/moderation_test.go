@@ -0,0 +1,39 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
+)
+
+func TestHasForbiddenLink(t *testing.T) {
+	cfg := config.Default()
+	cfg.Moderation.Links.AllowedDomains = []string{"github.com", "mvaldes.dev"}
+	a := &Actions{Config: cfg}
+
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{text: "hello chat", want: false},
+		{text: "great stream.thanks for the help", want: false},
+		{text: "ok.so what now", want: false},
+		{text: "v1.2 is out", want: false},
+		{text: "check spam.com", want: true},
+		{text: "free followers at bit.ly/abc", want: true},
+		{text: "SPAM.COM", want: true},
+		{text: "https://stream.thanks", want: true},
+		{text: "www.ok.so", want: true},
+		{text: "http://localhost.test/path", want: true},
+		{text: "my dotfiles github.com/mvaldes14/dotfiles", want: false},
+		{text: "https://gist.github.com/x", want: false},
+		{text: "www.mvaldes.dev and https://links.mvaldes.dev/stream", want: false},
+		{text: "github.com and evil.net", want: true},
+		{text: "notgithub.com", want: true},
+	}
+	for _, tt := range tests {
+		if got := a.hasForbiddenLink(tt.text); got != tt.want {
+			t.Errorf("hasForbiddenLink(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// Package actions handles Twitch chat commands and actions
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
+	"github.com/mvaldes14/twitch-bot/pkgs/locale"
+	"github.com/mvaldes14/twitch-bot/pkgs/subscriptions"
+)
+
+const (
+	// RuleCommand marks audit entries for actions a moderator ran through a chat command
+	RuleCommand = "command"
+	// defaultTimeout is used by !to when no duration is given
+	defaultTimeout = 10 * time.Minute
+	// maxMarkerDescription is the longest stream marker description Helix accepts
+	maxMarkerDescription = 140
+)
+
+// moderatorCommands are the built-in commands moderators use to act on chat and the stream through Helix
+func (a *Actions) moderatorCommands() []Command {
+	return []Command{
+		{
+			Name:        "so",
+			Aliases:     []string{"shoutout"},
+			Description: "Shouts out a channel",
+			Usage:       "<user>",
+			Permission:  PermissionModerator,
+			MinArgs:     1,
+			Handler:     a.shoutout,
+		},
+		{
+			Name:        "to",
+			Aliases:     []string{"timeout"},
+			Description: "Times out a chatter",
+			Usage:       "<user> [seconds] [reason]",
+			Permission:  PermissionModerator,
+			MinArgs:     1,
+			Handler:     a.timeoutCommand,
+		},
+		{
+			Name:        "ban",
+			Description: "Bans a chatter",
+			Usage:       "<user> [reason]",
+			Permission:  PermissionModerator,
+			MinArgs:     1,
+			Handler:     a.banCommand,
+		},
+		{
+			Name:        "unban",
+			Description: "Removes a ban or timeout",
+			Usage:       "<user>",
+			Permission:  PermissionModerator,
+			MinArgs:     1,
+			Handler:     a.unbanCommand,
+		},
+		{
+			Name:        "raid",
+			Description: "Raids another channel",
+			Usage:       "<channel>",
+			Permission:  PermissionModerator,
+			MinArgs:     1,
+			Handler:     a.raid,
+		},
+		{
+			Name:        "marker",
+			Description: "Adds a stream marker for the VOD",
+			Usage:       "[description]",
+			Permission:  PermissionModerator,
+			Handler:     a.marker,
+		},
+	}
+}
+
+// commandFailed tells the chatter a command could not be completed and returns err so ParseMessage logs it.
+// The reason given by Twitch is shown when there is one, other errors only get a generic answer
+func (a *Actions) commandFailed(inv Invocation, err error) error {
+	if err == nil || errors.Is(err, ErrUsage) {
+		return err
+	}
+	var helixErr *HelixError
+	if errors.As(err, &helixErr) && helixErr.Message != "" {
+		_ = a.Respond(inv, a.Catalog.Format(inv.Locale, locale.CommandFailed, "{user}", inv.ChatterName, "{reason}", helixErr.Message))
+		return err
+	}
+	_ = a.Respond(inv, a.Catalog.Format(inv.Locale, locale.CommandError, "{user}", inv.ChatterName))
+	return err
+}
+
+// lookupUser resolves a login, with or without @, to a Twitch user
+func (a *Actions) lookupUser(ctx context.Context, inv Invocation, login string) (subscriptions.HelixUser, error) {
+	login = strings.ToLower(strings.TrimPrefix(login, "@"))
+	var users subscriptions.UsersResponse
+	err := a.helixUserRequest(ctx, "get_users", http.MethodGet, "/users?"+url.Values{"login": {login}}.Encode(), inv.ChannelID, nil, &users)
+	if err != nil {
+		return subscriptions.HelixUser{}, err
+	}
+	if len(users.Data) == 0 {
+		return subscriptions.HelixUser{}, usageError("%s", a.Catalog.Format(inv.Locale, locale.UserNotFound, "{user}", login))
+	}
+	return users.Data[0], nil
+}
+
+// shoutout sends a Helix shoutout and a chat message with the channel's last category
+func (a *Actions) shoutout(ctx context.Context, inv Invocation) error {
+	user, err := a.lookupUser(ctx, inv, inv.Arg(0))
+	if err != nil {
+		return a.commandFailed(inv, err)
+	}
+
+	var channels subscriptions.ChannelInformationResponse
+	if err := a.helixUserRequest(ctx, "get_channel", http.MethodGet, "/channels?broadcaster_id="+user.ID, inv.ChannelID, nil, &channels); err != nil {
+		a.Log.Error("Failed to get channel information of "+user.Login, err)
+	}
+	game := ""
+	if len(channels.Data) > 0 {
+		game = channels.Data[0].GameName
+	}
+	message := a.Catalog.Format(inv.Locale, locale.ShoutoutNoGame, "{user}", user.DisplayName, "{login}", user.Login)
+	if game != "" {
+		message = a.Catalog.Format(inv.Locale, locale.Shoutout, "{user}", user.DisplayName, "{login}", user.Login, "{game}", game)
+	}
+	_ = a.SendMessage(inv.ChannelID, message)
+
+	// Helix shoutouts only work while live and are rate limited by Twitch, the chat message goes out regardless
+	moderatorID := a.Secrets.SenderID(inv.ChannelID)
+	query := url.Values{
+		"from_broadcaster_id": {inv.ChannelID},
+		"to_broadcaster_id":   {user.ID},
+		"moderator_id":        {moderatorID},
+	}
+	if err := a.helixUserRequest(ctx, "send_shoutout", http.MethodPost, "/chat/shoutouts?"+query.Encode(), moderatorID, nil, nil); err != nil {
+		a.Log.Error("Twitch did not send the shoutout to "+user.Login, err)
+	}
+	a.Log.Info(fmt.Sprintf("[MODERATOR: SHOUTOUT] %s by %s in channel %s", user.Login, inv.ChatterName, inv.ChannelID))
+	return nil
+}
+
+// timeoutCommand times out a chatter: `!to <user> [seconds] [reason]`, seconds may also be a duration such as 10m
+func (a *Actions) timeoutCommand(ctx context.Context, inv Invocation) error {
+	duration, reasonArgs := defaultTimeout, inv.Args[1:]
+	if len(reasonArgs) > 0 {
+		if parsed, ok := parseTimeout(reasonArgs[0]); ok {
+			duration, reasonArgs = parsed, reasonArgs[1:]
+		}
+	}
+	if duration < time.Second || duration > config.MaxTimeout {
+		return usageError("timeout must be between 1s and %s", config.MaxTimeout)
+	}
+	return a.moderateUser(ctx, inv, config.ModerationTimeout, duration, strings.Join(reasonArgs, " "))
+}
+
+// banCommand bans a chatter: `!ban <user> [reason]`
+func (a *Actions) banCommand(ctx context.Context, inv Invocation) error {
+	return a.moderateUser(ctx, inv, config.ModerationBan, 0, strings.Join(inv.Args[1:], " "))
+}
+
+// moderateUser bans or times out the user in the first argument, records it in the audit log and confirms in chat
+func (a *Actions) moderateUser(ctx context.Context, inv Invocation, action string, duration time.Duration, reason string) error {
+	user, err := a.lookupUser(ctx, inv, inv.Arg(0))
+	if err != nil {
+		return a.commandFailed(inv, err)
+	}
+	if reason == "" {
+		reason = "Moderated by " + inv.ChatterLogin
+	}
+	err = a.banUser(ctx, inv.ChannelID, user.ID, duration, reason)
+
+	entry := AuditEntry{
+		Time:      time.Now(),
+		UserID:    user.ID,
+		UserLogin: user.Login,
+		Rule:      RuleCommand,
+		Action:    action,
+		Moderator: inv.ChatterLogin,
+		Reason:    reason,
+	}
+	if duration > 0 {
+		entry.Duration = duration.String()
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	a.recordAudit(inv.ChannelID, entry)
+	if err != nil {
+		return a.commandFailed(inv, err)
+	}
+
+	a.Log.Info(fmt.Sprintf("[MODERATOR: %s] %s by %s in channel %s", strings.ToUpper(action), user.Login, inv.ChatterName, inv.ChannelID))
+	if action == config.ModerationTimeout {
+		return a.Respond(inv, a.Catalog.Format(inv.Locale, locale.TimedOut, "{user}", user.DisplayName, "{duration}", duration.String()))
+	}
+	return a.Respond(inv, a.Catalog.Format(inv.Locale, locale.Banned, "{user}", user.DisplayName))
+}
+
+// unbanCommand lifts a ban or timeout: `!unban <user>`
+func (a *Actions) unbanCommand(ctx context.Context, inv Invocation) error {
+	user, err := a.lookupUser(ctx, inv, inv.Arg(0))
+	if err != nil {
+		return a.commandFailed(inv, err)
+	}
+	moderatorID := a.Secrets.SenderID(inv.ChannelID)
+	query := url.Values{
+		"broadcaster_id": {inv.ChannelID},
+		"moderator_id":   {moderatorID},
+		"user_id":        {user.ID},
+	}
+	err = a.helixUserRequest(ctx, "unban_user", http.MethodDelete, "/moderation/bans?"+query.Encode(), moderatorID, nil, nil)
+
+	entry := AuditEntry{
+		Time:      time.Now(),
+		UserID:    user.ID,
+		UserLogin: user.Login,
+		Rule:      RuleCommand,
+		Action:    "unban",
+		Moderator: inv.ChatterLogin,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	a.recordAudit(inv.ChannelID, entry)
+	if err != nil {
+		return a.commandFailed(inv, err)
+	}
+	a.Log.Info(fmt.Sprintf("[MODERATOR: UNBAN] %s by %s in channel %s", user.Login, inv.ChatterName, inv.ChannelID))
+	return a.Respond(inv, a.Catalog.Format(inv.Locale, locale.Unbanned, "{user}", user.DisplayName))
+}
+
+// raid starts a raid to another channel with the broadcaster's token: `!raid <channel>`
+func (a *Actions) raid(ctx context.Context, inv Invocation) error {
+	target, err := a.lookupUser(ctx, inv, inv.Arg(0))
+	if err != nil {
+		return a.commandFailed(inv, err)
+	}
+	query := url.Values{
+		"from_broadcaster_id": {inv.ChannelID},
+		"to_broadcaster_id":   {target.ID},
+	}
+	if err := a.helixUserRequest(ctx, "start_raid", http.MethodPost, "/raids?"+query.Encode(), inv.ChannelID, nil, nil); err != nil {
+		return a.commandFailed(inv, err)
+	}
+	a.Log.Info(fmt.Sprintf("[MODERATOR: RAID] %s by %s from channel %s", target.Login, inv.ChatterName, inv.ChannelID))
+	return a.SendMessage(inv.ChannelID, a.Catalog.Format(inv.Locale, locale.Raiding, "{user}", target.DisplayName, "{login}", target.Login))
+}
+
+// marker adds a stream marker with the broadcaster's token: `!marker [description]`
+func (a *Actions) marker(ctx context.Context, inv Invocation) error {
+	description := inv.RawArgs
+	if utf8.RuneCountInString(description) > maxMarkerDescription {
+		return usageError("description must be at most %d characters", maxMarkerDescription)
+	}
+	payload := struct {
+		UserID      string `json:"user_id"`
+		Description string `json:"description,omitempty"`
+	}{
+		UserID:      inv.ChannelID,
+		Description: description,
+	}
+	var created subscriptions.StreamMarkerResponse
+	if err := a.helixUserRequest(ctx, "create_stream_marker", http.MethodPost, "/streams/markers", inv.ChannelID, payload, &created); err != nil {
+		return a.commandFailed(inv, err)
+	}
+	position := ""
+	if len(created.Data) > 0 {
+		position = formatUptime(time.Duration(created.Data[0].PositionSeconds) * time.Second)
+	}
+	a.Log.Info(fmt.Sprintf("[MODERATOR: MARKER] at %s by %s in channel %s", position, inv.ChatterName, inv.ChannelID))
+	return a.Respond(inv, a.Catalog.Format(inv.Locale, locale.MarkerCreated, "{position}", position))
+}
+
+// parseTimeout reads a timeout given in seconds ("600") or as a duration ("10m")
+func parseTimeout(value string) (time.Duration, bool) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	duration, err := time.ParseDuration(value)
+	return duration, err == nil
+}
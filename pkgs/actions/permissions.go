@@ -0,0 +1,66 @@
+// Package actions handles Twitch chat commands and actions
+package actions
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
+	"github.com/mvaldes14/twitch-bot/pkgs/subscriptions"
+)
+
+// Permission is the role of a chatter, higher levels include the lower ones
+type Permission int
+
+// Permission levels in the order of config.PermissionLevels
+const (
+	PermissionEveryone Permission = iota
+	PermissionSubscriber
+	PermissionVIP
+	PermissionModerator
+	PermissionBroadcaster
+)
+
+// badgePermissions maps chat badge set IDs to the role they grant
+var badgePermissions = map[string]Permission{
+	"broadcaster":    PermissionBroadcaster,
+	"lead_moderator": PermissionModerator,
+	"moderator":      PermissionModerator,
+	"vip":            PermissionVIP,
+	"subscriber":     PermissionSubscriber,
+	"founder":        PermissionSubscriber,
+}
+
+// ParsePermission converts a level name from config.PermissionLevels, an empty name means everyone
+func ParsePermission(level string) (Permission, error) {
+	if level == "" {
+		return PermissionEveryone, nil
+	}
+	i := slices.Index(config.PermissionLevels, level)
+	if i < 0 {
+		return PermissionEveryone, fmt.Errorf("%w: unknown permission %q", ErrInvalidCommand, level)
+	}
+	return Permission(i), nil
+}
+
+func (p Permission) String() string {
+	if p < 0 || int(p) >= len(config.PermissionLevels) {
+		return fmt.Sprintf("permission(%d)", int(p))
+	}
+	return config.PermissionLevels[p]
+}
+
+// chatterPermission returns the highest role of the chatter from their badges.
+// The chatter is the broadcaster when their ID matches the channel, whatever badges they show.
+func chatterPermission(msg subscriptions.ChatMessageEvent) Permission {
+	if msg.Event.ChatterUserID != "" && msg.Event.ChatterUserID == msg.Event.BroadcasterUserID {
+		return PermissionBroadcaster
+	}
+	permission := PermissionEveryone
+	for _, badge := range msg.Event.Badges {
+		if granted, ok := badgePermissions[badge.SetID]; ok && granted > permission {
+			permission = granted
+		}
+	}
+	return permission
+}
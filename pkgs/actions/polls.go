@@ -0,0 +1,420 @@
+// Package actions handles Twitch chat commands and actions
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/locale"
+	"github.com/mvaldes14/twitch-bot/pkgs/subscriptions"
+	"github.com/redis/go-redis/v9"
+)
+
+// Limits of Create Poll and Create Prediction
+const (
+	defaultPollDuration   = time.Minute
+	minPollDuration       = 15 * time.Second
+	minPredictionWindow   = 30 * time.Second
+	maxPollDuration       = 30 * time.Minute
+	maxPollTitle          = 60
+	maxPredictionTitle    = 45
+	maxChoiceTitle        = 25
+	maxPollChoices        = 5
+	maxPredictionOutcomes = 10
+)
+
+// EventSub poll and prediction event types
+const (
+	PollBegin          = "channel.poll.begin"
+	PollProgress       = "channel.poll.progress"
+	PollEnd            = "channel.poll.end"
+	PredictionBegin    = "channel.prediction.begin"
+	PredictionProgress = "channel.prediction.progress"
+	PredictionLock     = "channel.prediction.lock"
+	PredictionEnd      = "channel.prediction.end"
+)
+
+// overlayKey is the Redis hash holding the latest poll and prediction of a channel for overlays
+func overlayKey(channelID string) string {
+	return "overlay:" + channelID
+}
+
+// pollCommands are the built-in commands moderators use to run Twitch polls and predictions from chat
+func (a *Actions) pollCommands() []Command {
+	return []Command{
+		{
+			Name:        "poll",
+			Description: "Starts a Twitch poll",
+			Usage:       `"<question>" <choice> | <choice> [duration]`,
+			Permission:  PermissionModerator,
+			MinArgs:     2,
+			Handler:     a.startPoll,
+		},
+		{
+			Name:        "predict",
+			Description: "Starts a Twitch prediction",
+			Usage:       `"<question>" <outcome> | <outcome> [duration]`,
+			Permission:  PermissionModerator,
+			MinArgs:     2,
+			Handler:     a.startPrediction,
+		},
+		{
+			Name:        "resolve",
+			Description: "Pays out the open prediction to an outcome",
+			Usage:       "<outcome>",
+			Permission:  PermissionModerator,
+			MinArgs:     1,
+			Handler:     a.resolvePrediction,
+		},
+		{
+			Name:        "cancel",
+			Description: "Cancels the open prediction and refunds the points, or ends the running poll",
+			Permission:  PermissionModerator,
+			Handler:     a.cancelPrediction,
+		},
+	}
+}
+
+// parseChoices splits `"<question>" a | b | c [duration]` into the question, the choices and the duration.
+// The duration must have a unit, e.g. 60s or 2m, so a numeric last choice is not taken for one
+func parseChoices(args []string, maxTitle, maxChoices int, minDuration time.Duration) (string, []string, time.Duration, error) {
+	title, rest := strings.TrimSpace(args[0]), args[1:]
+	duration := defaultPollDuration
+	if len(rest) > 1 {
+		if parsed, err := time.ParseDuration(rest[len(rest)-1]); err == nil {
+			duration, rest = parsed, rest[:len(rest)-1]
+		}
+	}
+	if title == "" || utf8.RuneCountInString(title) > maxTitle {
+		return "", nil, 0, usageError("the question must be 1-%d characters, quote it when it has spaces", maxTitle)
+	}
+	if duration < minDuration || duration > maxPollDuration {
+		return "", nil, 0, usageError("the duration must be between %s and %s", minDuration, maxPollDuration)
+	}
+
+	var choices []string
+	for _, choice := range strings.Split(strings.Join(rest, " "), "|") {
+		choice = strings.TrimSpace(choice)
+		if choice == "" || utf8.RuneCountInString(choice) > maxChoiceTitle {
+			return "", nil, 0, usageError("choices must be 1-%d characters, separated by |", maxChoiceTitle)
+		}
+		choices = append(choices, choice)
+	}
+	if len(choices) < 2 || len(choices) > maxChoices {
+		return "", nil, 0, usageError("give between 2 and %d choices separated by |", maxChoices)
+	}
+	return title, choices, duration, nil
+}
+
+// startPoll starts a poll with the broadcaster's token: `!poll "Question" opt1 | opt2 | opt3 60s`.
+// The channel.poll.begin event announces it in chat
+func (a *Actions) startPoll(ctx context.Context, inv Invocation) error {
+	title, choices, duration, err := parseChoices(inv.Args, maxPollTitle, maxPollChoices, minPollDuration)
+	if err != nil {
+		return err
+	}
+	type choice struct {
+		Title string `json:"title"`
+	}
+	payload := struct {
+		BroadcasterID string   `json:"broadcaster_id"`
+		Title         string   `json:"title"`
+		Choices       []choice `json:"choices"`
+		Duration      int      `json:"duration"`
+	}{
+		BroadcasterID: inv.ChannelID,
+		Title:         title,
+		Duration:      int(duration.Seconds()),
+	}
+	for _, title := range choices {
+		payload.Choices = append(payload.Choices, choice{Title: title})
+	}
+	if err := a.helixUserRequest(ctx, "create_poll", http.MethodPost, "/polls", inv.ChannelID, payload, nil); err != nil {
+		return a.commandFailed(inv, err)
+	}
+	a.Log.Info(fmt.Sprintf("[POLL: STARTED] %q for %s by %s in channel %s", title, duration, inv.ChatterName, inv.ChannelID))
+	return nil
+}
+
+// startPrediction starts a prediction with the broadcaster's token: `!predict "Question" win | lose 2m`.
+// The channel.prediction.begin event announces it in chat
+func (a *Actions) startPrediction(ctx context.Context, inv Invocation) error {
+	title, outcomes, window, err := parseChoices(inv.Args, maxPredictionTitle, maxPredictionOutcomes, minPredictionWindow)
+	if err != nil {
+		return err
+	}
+	type outcome struct {
+		Title string `json:"title"`
+	}
+	payload := struct {
+		BroadcasterID    string    `json:"broadcaster_id"`
+		Title            string    `json:"title"`
+		Outcomes         []outcome `json:"outcomes"`
+		PredictionWindow int       `json:"prediction_window"`
+	}{
+		BroadcasterID:    inv.ChannelID,
+		Title:            title,
+		PredictionWindow: int(window.Seconds()),
+	}
+	for _, title := range outcomes {
+		payload.Outcomes = append(payload.Outcomes, outcome{Title: title})
+	}
+	if err := a.helixUserRequest(ctx, "create_prediction", http.MethodPost, "/predictions", inv.ChannelID, payload, nil); err != nil {
+		return a.commandFailed(inv, err)
+	}
+	a.Log.Info(fmt.Sprintf("[PREDICTION: STARTED] %q for %s by %s in channel %s", title, window, inv.ChatterName, inv.ChannelID))
+	return nil
+}
+
+// openPrediction returns the latest prediction of a channel when it is still active or locked
+func (a *Actions) openPrediction(ctx context.Context, channelID string) (subscriptions.Prediction, bool, error) {
+	query := url.Values{"broadcaster_id": {channelID}, "first": {"1"}}
+	var predictions subscriptions.PredictionsResponse
+	if err := a.helixUserRequest(ctx, "get_predictions", http.MethodGet, "/predictions?"+query.Encode(), channelID, nil, &predictions); err != nil {
+		return subscriptions.Prediction{}, false, err
+	}
+	if len(predictions.Data) == 0 {
+		return subscriptions.Prediction{}, false, nil
+	}
+	prediction := predictions.Data[0]
+	status := strings.ToUpper(prediction.Status)
+	return prediction, status == "ACTIVE" || status == "LOCKED", nil
+}
+
+// endPrediction resolves or cancels a prediction, winningOutcomeID is only sent when resolving
+func (a *Actions) endPrediction(ctx context.Context, channelID, predictionID, status, winningOutcomeID string) error {
+	payload := struct {
+		BroadcasterID    string `json:"broadcaster_id"`
+		ID               string `json:"id"`
+		Status           string `json:"status"`
+		WinningOutcomeID string `json:"winning_outcome_id,omitempty"`
+	}{
+		BroadcasterID:    channelID,
+		ID:               predictionID,
+		Status:           status,
+		WinningOutcomeID: winningOutcomeID,
+	}
+	return a.helixUserRequest(ctx, "end_prediction", http.MethodPatch, "/predictions", channelID, payload, nil)
+}
+
+// resolvePrediction pays out the open prediction: `!resolve <outcome>`, the outcome is its number or its title
+func (a *Actions) resolvePrediction(ctx context.Context, inv Invocation) error {
+	prediction, open, err := a.openPrediction(ctx, inv.ChannelID)
+	if err != nil {
+		return a.commandFailed(inv, err)
+	}
+	if !open {
+		return usageError("%s", a.Catalog.Format(inv.Locale, locale.NoPrediction))
+	}
+	outcome, ok := findOutcome(prediction.Outcomes, inv.RawArgs)
+	if !ok {
+		titles := make([]string, 0, len(prediction.Outcomes))
+		for _, outcome := range prediction.Outcomes {
+			titles = append(titles, outcome.Title)
+		}
+		return usageError("%s", a.Catalog.Format(inv.Locale, locale.OutcomeNotFound,
+			"{outcome}", inv.RawArgs,
+			"{outcomes}", strings.Join(titles, " | "),
+		))
+	}
+	if err := a.endPrediction(ctx, inv.ChannelID, prediction.ID, "RESOLVED", outcome.ID); err != nil {
+		return a.commandFailed(inv, err)
+	}
+	a.Log.Info(fmt.Sprintf("[PREDICTION: RESOLVED] %q to %q by %s in channel %s", prediction.Title, outcome.Title, inv.ChatterName, inv.ChannelID))
+	return nil
+}
+
+// findOutcome matches an outcome by its 1-based number, its title or the start of its title, ignoring case
+func findOutcome(outcomes []subscriptions.PredictionOutcome, value string) (subscriptions.PredictionOutcome, bool) {
+	value = strings.TrimSpace(value)
+	if n, err := strconv.Atoi(value); err == nil && n >= 1 && n <= len(outcomes) {
+		return outcomes[n-1], true
+	}
+	for _, outcome := range outcomes {
+		if strings.EqualFold(outcome.Title, value) {
+			return outcome, true
+		}
+	}
+	var match subscriptions.PredictionOutcome
+	matches := 0
+	for _, outcome := range outcomes {
+		if value != "" && strings.HasPrefix(strings.ToLower(outcome.Title), strings.ToLower(value)) {
+			match = outcome
+			matches++
+		}
+	}
+	// An ambiguous prefix could pay out the wrong outcome
+	return match, matches == 1
+}
+
+// cancelPrediction cancels the open prediction and refunds its points, or ends the running poll when there
+// is no prediction: `!cancel`
+func (a *Actions) cancelPrediction(ctx context.Context, inv Invocation) error {
+	prediction, open, err := a.openPrediction(ctx, inv.ChannelID)
+	if err != nil {
+		return a.commandFailed(inv, err)
+	}
+	if open {
+		if err := a.endPrediction(ctx, inv.ChannelID, prediction.ID, "CANCELED", ""); err != nil {
+			return a.commandFailed(inv, err)
+		}
+		a.Log.Info(fmt.Sprintf("[PREDICTION: CANCELED] %q by %s in channel %s", prediction.Title, inv.ChatterName, inv.ChannelID))
+		return nil
+	}
+
+	query := url.Values{"broadcaster_id": {inv.ChannelID}, "first": {"1"}}
+	var polls subscriptions.PollsResponse
+	if err := a.helixUserRequest(ctx, "get_polls", http.MethodGet, "/polls?"+query.Encode(), inv.ChannelID, nil, &polls); err != nil {
+		return a.commandFailed(inv, err)
+	}
+	if len(polls.Data) == 0 || !strings.EqualFold(polls.Data[0].Status, "ACTIVE") {
+		return usageError("%s", a.Catalog.Format(inv.Locale, locale.NothingToCancel))
+	}
+	poll := polls.Data[0]
+	payload := struct {
+		BroadcasterID string `json:"broadcaster_id"`
+		ID            string `json:"id"`
+		Status        string `json:"status"`
+	}{
+		BroadcasterID: inv.ChannelID,
+		ID:            poll.ID,
+		Status:        "TERMINATED",
+	}
+	if err := a.helixUserRequest(ctx, "end_poll", http.MethodPatch, "/polls", inv.ChannelID, payload, nil); err != nil {
+		return a.commandFailed(inv, err)
+	}
+	a.Log.Info(fmt.Sprintf("[POLL: ENDED] %q by %s in channel %s", poll.Title, inv.ChatterName, inv.ChannelID))
+	return nil
+}
+
+// PollUpdated keeps a channel.poll.* event for overlays and announces the start and the result of the poll in chat
+func (a *Actions) PollUpdated(channelID, eventType string, poll subscriptions.Poll) error {
+	a.storeOverlay(channelID, "poll", poll)
+	switch eventType {
+	case PollBegin:
+		choices := make([]string, 0, len(poll.Choices))
+		for _, choice := range poll.Choices {
+			choices = append(choices, choice.Title)
+		}
+		return a.SendMessage(channelID, a.Translate(channelID, locale.PollStarted,
+			"{title}", poll.Title,
+			"{choices}", strings.Join(choices, " | "),
+			"{duration}", remaining(poll.StartedAt, poll.EndsAt),
+		))
+	case PollEnd:
+		// Archived polls were already announced when they completed or were terminated
+		if strings.EqualFold(poll.Status, "archived") || len(poll.Choices) == 0 {
+			return nil
+		}
+		var winners, results []string
+		top := -1
+		for _, choice := range poll.Choices {
+			results = append(results, fmt.Sprintf("%s %d", choice.Title, choice.Votes))
+			switch {
+			case choice.Votes > top:
+				top, winners = choice.Votes, []string{choice.Title}
+			case choice.Votes == top:
+				winners = append(winners, choice.Title)
+			}
+		}
+		return a.SendMessage(channelID, a.Translate(channelID, locale.PollEnded,
+			"{title}", poll.Title,
+			"{winner}", strings.Join(winners, " / "),
+			"{votes}", strconv.Itoa(top),
+			"{results}", strings.Join(results, ", "),
+		))
+	}
+	return nil
+}
+
+// PredictionUpdated keeps a channel.prediction.* event for overlays and announces its start, lock and end in chat
+func (a *Actions) PredictionUpdated(channelID, eventType string, prediction subscriptions.Prediction) error {
+	a.storeOverlay(channelID, "prediction", prediction)
+	switch eventType {
+	case PredictionBegin:
+		outcomes := make([]string, 0, len(prediction.Outcomes))
+		for _, outcome := range prediction.Outcomes {
+			outcomes = append(outcomes, outcome.Title)
+		}
+		return a.SendMessage(channelID, a.Translate(channelID, locale.PredictionStarted,
+			"{title}", prediction.Title,
+			"{outcomes}", strings.Join(outcomes, " | "),
+			"{duration}", remaining(prediction.StartedAt, prediction.LocksAt),
+		))
+	case PredictionLock:
+		return a.SendMessage(channelID, a.Translate(channelID, locale.PredictionLocked, "{title}", prediction.Title))
+	case PredictionEnd:
+		if strings.EqualFold(prediction.Status, "canceled") {
+			return a.SendMessage(channelID, a.Translate(channelID, locale.PredictionCanceled, "{title}", prediction.Title))
+		}
+		points := 0
+		for _, outcome := range prediction.Outcomes {
+			points += outcome.ChannelPoints
+		}
+		for _, outcome := range prediction.Outcomes {
+			if outcome.ID == prediction.WinningOutcomeID {
+				return a.SendMessage(channelID, a.Translate(channelID, locale.PredictionResolved,
+					"{title}", prediction.Title,
+					"{outcome}", outcome.Title,
+					"{users}", strconv.Itoa(outcome.Users),
+					"{points}", strconv.Itoa(points),
+				))
+			}
+		}
+	}
+	return nil
+}
+
+// remaining renders the time from start until end for chat, e.g. "1m0s"
+func remaining(start time.Time, end *time.Time) string {
+	if end == nil || start.IsZero() {
+		return ""
+	}
+	return end.Sub(start).Round(time.Second).String()
+}
+
+// storeOverlay keeps the latest poll or prediction of a channel, overlays read it with CurrentPoll and CurrentPrediction
+func (a *Actions) storeOverlay(channelID, field string, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		a.Log.Error("Failed to marshal "+field+" for overlays", err)
+		return
+	}
+	if err := a.Cache.SetField(overlayKey(channelID), field, string(data)); err != nil {
+		a.Log.Error("Failed to store "+field+" for overlays", err)
+	}
+}
+
+// loadOverlay reads the latest poll or prediction of a channel into target, false when there was none
+func (a *Actions) loadOverlay(channelID, field string, target any) (bool, error) {
+	data, err := a.Cache.GetField(overlayKey(channelID), field)
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(data), target)
+}
+
+// CurrentPoll returns the latest poll of a channel with its votes, false when no poll ran since the bot started tracking
+func (a *Actions) CurrentPoll(channelID string) (subscriptions.Poll, bool, error) {
+	var poll subscriptions.Poll
+	ok, err := a.loadOverlay(channelID, "poll", &poll)
+	return poll, ok, err
+}
+
+// CurrentPrediction returns the latest prediction of a channel with its outcomes, false when none ran yet
+func (a *Actions) CurrentPrediction(channelID string) (subscriptions.Prediction, bool, error) {
+	var prediction subscriptions.Prediction
+	ok, err := a.loadOverlay(channelID, "prediction", &prediction)
+	return prediction, ok, err
+}
@@ -0,0 +1,95 @@
+package actions
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/subscriptions"
+)
+
+func TestParseChoices(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		wantTitle    string
+		wantChoices  []string
+		wantDuration time.Duration
+		wantUsage    bool
+	}{
+		{name: "choices without spaces", args: []string{"Ship it?", "yes|no"}, wantTitle: "Ship it?", wantChoices: []string{"yes", "no"}, wantDuration: defaultPollDuration},
+		{name: "choices with spaces", args: []string{"Ship it?", "yes", "|", "not", "yet"}, wantTitle: "Ship it?", wantChoices: []string{"yes", "not yet"}, wantDuration: defaultPollDuration},
+		{name: "duration", args: []string{"Ship it?", "yes|no|maybe", "90s"}, wantTitle: "Ship it?", wantChoices: []string{"yes", "no", "maybe"}, wantDuration: 90 * time.Second},
+		{name: "apostrophe in choice", args: []string{"Ship it?", "yes", "|", "can't"}, wantTitle: "Ship it?", wantChoices: []string{"yes", "can't"}, wantDuration: defaultPollDuration},
+		{name: "lone duration is a choice", args: []string{"Ship it?", "60s"}, wantUsage: true},
+		{name: "one choice", args: []string{"Ship it?", "yes"}, wantUsage: true},
+		{name: "empty choice", args: []string{"Ship it?", "yes||no"}, wantUsage: true},
+		{name: "too many choices", args: []string{"Q", "a|b|c|d|e|f"}, wantUsage: true},
+		{name: "choice too long", args: []string{"Q", "yes|this choice is longer than allowed"}, wantUsage: true},
+		{name: "empty question", args: []string{" ", "yes|no"}, wantUsage: true},
+		{name: "question too long", args: []string{strings.Repeat("q", maxPollTitle+1), "yes|no"}, wantUsage: true},
+		{name: "duration too short", args: []string{"Q", "yes|no", "5s"}, wantUsage: true},
+		{name: "duration too long", args: []string{"Q", "yes|no", "1h"}, wantUsage: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, choices, duration, err := parseChoices(tt.args, maxPollTitle, maxPollChoices, minPollDuration)
+			if errors.Is(err, ErrUsage) != tt.wantUsage {
+				t.Fatalf("parseChoices(%q) error = %v, want usage error %v", tt.args, err, tt.wantUsage)
+			}
+			if tt.wantUsage {
+				return
+			}
+			if title != tt.wantTitle || duration != tt.wantDuration || !reflect.DeepEqual(choices, tt.wantChoices) {
+				t.Errorf("parseChoices(%q) = %q, %q, %s, want %q, %q, %s", tt.args, title, choices, duration, tt.wantTitle, tt.wantChoices, tt.wantDuration)
+			}
+		})
+	}
+}
+
+func TestPollCommandsAcceptCompactChoices(t *testing.T) {
+	// `!poll "Q" yes|no` is two arguments, the handler checks the choices themselves
+	inv, _, err := parseInvocation("!", chatMessage(t, `!poll "Ship it?" yes|no`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cmd := range (&Actions{}).pollCommands() {
+		if (cmd.Name == "poll" || cmd.Name == "predict") && len(inv.Args) < cmd.MinArgs {
+			t.Errorf("!%s needs %d arguments, %q has %d", cmd.Name, cmd.MinArgs, inv.RawArgs, len(inv.Args))
+		}
+	}
+}
+
+func TestFindOutcome(t *testing.T) {
+	outcomes := []subscriptions.PredictionOutcome{
+		{ID: "1", Title: "Yes"},
+		{ID: "2", Title: "No"},
+		{ID: "3", Title: "Not sure"},
+		{ID: "4", Title: "Maybe later"},
+	}
+	tests := []struct {
+		value  string
+		wantID string
+		wantOK bool
+	}{
+		{value: "1", wantID: "1", wantOK: true},
+		{value: " 4 ", wantID: "4", wantOK: true},
+		{value: "0"},
+		{value: "5"},
+		{value: "no", wantID: "2", wantOK: true},
+		{value: "NOT SURE", wantID: "3", wantOK: true},
+		{value: "may", wantID: "4", wantOK: true},
+		{value: "n"},
+		{value: "y", wantID: "1", wantOK: true},
+		{value: ""},
+		{value: "never"},
+	}
+	for _, tt := range tests {
+		got, ok := findOutcome(outcomes, tt.value)
+		if ok != tt.wantOK || (ok && got.ID != tt.wantID) {
+			t.Errorf("findOutcome(%q) = %q, %v, want %q, %v", tt.value, got.ID, ok, tt.wantID, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// Package actions handles Twitch chat commands and actions
+package actions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// PresetSourceConfig marks presets defined in the config file, they cannot be changed through the API
+	PresetSourceConfig = "config"
+	// PresetSourceAPI marks presets added at runtime and stored in Redis
+	PresetSourceAPI = "api"
+	// DefaultPreset is the channel's own title prefix, category, tags and language, `!preset default` switches back to it
+	DefaultPreset = "default"
+)
+
+var (
+	// ErrPresetNotFound is returned when a channel has no preset with the given name
+	ErrPresetNotFound = errors.New("preset not found")
+	// ErrPresetConflict is returned when a preset name is taken or belongs to a configured preset
+	ErrPresetConflict = errors.New("preset already exists")
+	// ErrInvalidPreset is returned when a preset has an unusable name or channel info
+	ErrInvalidPreset = errors.New("invalid preset")
+)
+
+// Preset is a named set of channel info applied together with !preset
+type Preset struct {
+	Name string `json:"name"`
+	// Title is a title template, {title} is replaced with the text given to !preset, !title or !today
+	Title      string   `json:"title,omitempty"`
+	CategoryID string   `json:"category_id,omitempty"`
+	Category   string   `json:"category,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Language   string   `json:"language,omitempty"`
+	Source     string   `json:"source"`
+}
+
+// presetsKey is the Redis hash holding a channel's runtime presets, one field per name
+func presetsKey(channelID string) string {
+	return "presets:" + channelID
+}
+
+// presetStateKey is the Redis hash remembering the active preset of a channel
+func presetStateKey(channelID string) string {
+	return "presets:" + channelID + ":state"
+}
+
+// channelPreset is the preset made of a channel's own settings
+func channelPreset(channel config.Channel) Preset {
+	return Preset{
+		Name:       DefaultPreset,
+		Title:      channel.TitlePrefix + "{title}",
+		CategoryID: channel.CategoryID,
+		Tags:       channel.Tags,
+		Language:   channel.Language,
+		Source:     PresetSourceConfig,
+	}
+}
+
+// ListPresets returns the configured and runtime presets of a channel sorted by name
+func (a *Actions) ListPresets(channelID string) ([]Preset, error) {
+	channel, ok := a.Config.Channel(channelID)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown channel %s", ErrInvalidPreset, channelID)
+	}
+	var presets []Preset
+	configured := map[string]bool{}
+	for _, preset := range a.Config.ChannelPresets(channel) {
+		configured[preset.Name] = true
+		presets = append(presets, Preset{
+			Name:       preset.Name,
+			Title:      preset.Title,
+			CategoryID: preset.CategoryID,
+			Category:   preset.Category,
+			Tags:       preset.Tags,
+			Language:   preset.Language,
+			Source:     PresetSourceConfig,
+		})
+	}
+
+	fields, err := a.Cache.GetFields(presetsKey(channelID))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range fields {
+		// A configured preset added after the runtime one takes its place
+		if configured[name] {
+			continue
+		}
+		var preset Preset
+		if err := json.Unmarshal([]byte(value), &preset); err != nil {
+			a.Log.Error("Skipping unreadable runtime preset "+name, err)
+			continue
+		}
+		preset.Source = PresetSourceAPI
+		presets = append(presets, preset)
+	}
+	sort.Slice(presets, func(i, j int) bool { return presets[i].Name < presets[j].Name })
+	return presets, nil
+}
+
+// GetPreset finds a preset of a channel by name, DefaultPreset is the channel's own settings
+func (a *Actions) GetPreset(channelID, name string) (Preset, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == DefaultPreset {
+		channel, ok := a.Config.Channel(channelID)
+		if !ok {
+			return Preset{}, fmt.Errorf("%w: unknown channel %s", ErrInvalidPreset, channelID)
+		}
+		return channelPreset(channel), nil
+	}
+	presets, err := a.ListPresets(channelID)
+	if err != nil {
+		return Preset{}, err
+	}
+	for _, preset := range presets {
+		if preset.Name == name {
+			return preset, nil
+		}
+	}
+	return Preset{}, fmt.Errorf("%w: %s", ErrPresetNotFound, name)
+}
+
+// CreatePreset adds a runtime preset, failing if a preset with the same name exists
+func (a *Actions) CreatePreset(channelID string, preset Preset) (Preset, error) {
+	if err := a.validatePreset(channelID, &preset); err != nil {
+		return preset, err
+	}
+	if _, err := a.Cache.GetField(presetsKey(channelID), preset.Name); err == nil {
+		return preset, fmt.Errorf("%w: %s", ErrPresetConflict, preset.Name)
+	} else if !errors.Is(err, redis.Nil) {
+		return preset, err
+	}
+	return preset, a.storePreset(channelID, preset)
+}
+
+// UpdatePreset replaces an existing runtime preset
+func (a *Actions) UpdatePreset(channelID string, preset Preset) (Preset, error) {
+	if err := a.validatePreset(channelID, &preset); err != nil {
+		return preset, err
+	}
+	if _, err := a.Cache.GetField(presetsKey(channelID), preset.Name); errors.Is(err, redis.Nil) {
+		return preset, ErrPresetNotFound
+	} else if err != nil {
+		return preset, err
+	}
+	return preset, a.storePreset(channelID, preset)
+}
+
+// DeletePreset removes a runtime preset, a channel using it switches back to DefaultPreset
+func (a *Actions) DeletePreset(channelID, name string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	removed, err := a.Cache.DeleteField(presetsKey(channelID), name)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrPresetNotFound
+	}
+	if active, err := a.Cache.GetField(presetStateKey(channelID), "active"); err == nil && active == name {
+		if _, err := a.Cache.DeleteField(presetStateKey(channelID), "active"); err != nil {
+			a.Log.Error("Failed to reset active preset of channel "+channelID, err)
+		}
+	}
+	return nil
+}
+
+func (a *Actions) storePreset(channelID string, preset Preset) error {
+	preset.Source = ""
+	value, err := json.Marshal(preset)
+	if err != nil {
+		return err
+	}
+	return a.Cache.SetField(presetsKey(channelID), preset.Name, string(value))
+}
+
+// validatePreset normalizes a runtime preset and rejects names used by configured presets
+func (a *Actions) validatePreset(channelID string, preset *Preset) error {
+	channel, ok := a.Config.Channel(channelID)
+	if !ok {
+		return fmt.Errorf("%w: unknown channel %s", ErrInvalidPreset, channelID)
+	}
+	preset.Name = strings.ToLower(strings.TrimSpace(preset.Name))
+	preset.Title = strings.TrimSpace(preset.Title)
+	preset.Category = strings.TrimSpace(preset.Category)
+	preset.Source = PresetSourceAPI
+	if !config.ValidCommandName(preset.Name) || preset.Name == DefaultPreset {
+		return fmt.Errorf("%w: name %q must be a single word other than %s", ErrInvalidPreset, preset.Name, DefaultPreset)
+	}
+	if preset.Title == "" && preset.CategoryID == "" && preset.Category == "" && len(preset.Tags) == 0 && preset.Language == "" {
+		return fmt.Errorf("%w: set at least one of title, category_id, category, tags or language", ErrInvalidPreset)
+	}
+	if utf8.RuneCountInString(preset.Title) > maxTitleLength {
+		return fmt.Errorf("%w: title must be at most %d characters", ErrInvalidPreset, maxTitleLength)
+	}
+	if preset.CategoryID != "" {
+		if _, err := strconv.ParseUint(preset.CategoryID, 10, 64); err != nil {
+			return fmt.Errorf("%w: category_id must be a numeric Twitch category ID", ErrInvalidPreset)
+		}
+	}
+	if len(preset.Tags) > config.MaxTags {
+		return fmt.Errorf("%w: at most %d tags", ErrInvalidPreset, config.MaxTags)
+	}
+	for _, tag := range preset.Tags {
+		if !config.ValidTag(tag) {
+			return fmt.Errorf("%w: tag %q must be 1-%d letters or digits", ErrInvalidPreset, tag, config.MaxTagLength)
+		}
+	}
+	for _, configured := range a.Config.ChannelPresets(channel) {
+		if configured.Name == preset.Name {
+			return fmt.Errorf("%w: %s is defined in the config file", ErrPresetConflict, preset.Name)
+		}
+	}
+	return nil
+}
+
+// activePreset is the preset a channel last switched to, DefaultPreset when none or when it no longer exists
+func (a *Actions) activePreset(channelID string) (Preset, error) {
+	name, err := a.Cache.GetField(presetStateKey(channelID), "active")
+	if err != nil && !errors.Is(err, redis.Nil) {
+		a.Log.Error("Failed to read active preset of channel "+channelID, err)
+	}
+	if name == "" {
+		name = DefaultPreset
+	}
+	preset, err := a.GetPreset(channelID, name)
+	if errors.Is(err, ErrPresetNotFound) {
+		return a.GetPreset(channelID, DefaultPreset)
+	}
+	return preset, err
+}
+
+// setActivePreset remembers the preset a channel switched to
+func (a *Actions) setActivePreset(channelID, name string) error {
+	if name == DefaultPreset {
+		_, err := a.Cache.DeleteField(presetStateKey(channelID), "active")
+		return err
+	}
+	return a.Cache.SetField(presetStateKey(channelID), "active", name)
+}
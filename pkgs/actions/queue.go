@@ -0,0 +1,152 @@
+// Package actions handles Twitch chat commands and actions
+package actions
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/telemetry"
+)
+
+const (
+	messageQueueSize = 256
+	// Twitch allows 20 chat messages every 30 seconds to senders that are not moderators, stay within it
+	chatRateLimit   = 20
+	chatRateWindow  = 30 * time.Second
+	maxSendAttempts = 4
+	sendBackoff     = time.Second
+	httpTimeout     = 10 * time.Second
+)
+
+var (
+	// ErrQueueFull is returned by SendMessage when the outbound queue cannot take more messages
+	ErrQueueFull = errors.New("outbound message queue is full")
+	// errRetryable marks send failures worth retrying: 429, 5xx and network errors
+	errRetryable = errors.New("retryable send failure")
+)
+
+// outboundMessage is a chat message waiting in the queue
+type outboundMessage struct {
+	channelID string
+	// replyTo is the chat message this one replies to, empty for a regular message
+	replyTo  string
+	text     string
+	queuedAt time.Time
+}
+
+// sendLimiter spaces chat sends using a sliding window of recent sends and the Ratelimit-* headers of Helix
+type sendLimiter struct {
+	mu           sync.Mutex
+	sent         []time.Time
+	blockedUntil time.Time
+}
+
+// delay returns how long to wait before the next send is allowed
+func (l *sendLimiter) delay(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	recent := l.sent[:0]
+	for _, at := range l.sent {
+		if now.Sub(at) < chatRateWindow {
+			recent = append(recent, at)
+		}
+	}
+	l.sent = recent
+
+	var wait time.Duration
+	if len(l.sent) >= chatRateLimit {
+		wait = l.sent[0].Add(chatRateWindow).Sub(now)
+	}
+	if blocked := l.blockedUntil.Sub(now); blocked > wait {
+		wait = blocked
+	}
+	return wait
+}
+
+// record counts a send towards the window
+func (l *sendLimiter) record(at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sent = append(l.sent, at)
+}
+
+// observe pauses sending until Ratelimit-Reset once Helix reports no remaining requests
+func (l *sendLimiter) observe(header http.Header) {
+	if header.Get("Ratelimit-Remaining") != "0" {
+		return
+	}
+	reset, err := strconv.ParseInt(header.Get("Ratelimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Unix(reset, 0); until.After(l.blockedUntil) {
+		l.blockedUntil = until
+	}
+}
+
+// wait blocks until the limiter allows a send, false when ctx is cancelled first
+func (l *sendLimiter) wait(ctx context.Context) bool {
+	for {
+		delay := l.delay(time.Now())
+		if delay <= 0 {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+	}
+}
+
+// StartMessageQueue delivers queued chat messages one at a time within the rate limits until ctx is cancelled
+func (a *Actions) StartMessageQueue(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				if pending := len(a.queue); pending > 0 {
+					a.Log.Info("Message queue stopped with " + strconv.Itoa(pending) + " messages pending")
+				}
+				return
+			case msg := <-a.queue:
+				telemetry.RecordMessageQueueDepth(ctx, int64(len(a.queue)))
+				if !a.limiter.wait(ctx) {
+					return
+				}
+				if err := a.deliverMessage(ctx, msg); err != nil {
+					a.Log.Error("Dropping chat message for channel "+msg.channelID, err)
+				}
+			}
+		}
+	}()
+}
+
+// enqueueMessages adds the parts of a message to the outbound queue without blocking.
+// Either every part is queued, back to back, or none is, so chat never sees half of a split message
+func (a *Actions) enqueueMessages(channelID, replyTo string, parts []string) error {
+	a.queueMu.Lock()
+	defer a.queueMu.Unlock()
+	// Only the worker takes from the queue, so the room checked here cannot shrink before the parts are added
+	if cap(a.queue)-len(a.queue) < len(parts) {
+		telemetry.IncrementMessageSent(context.Background(), "dropped")
+		return ErrQueueFull
+	}
+	for _, part := range parts {
+		a.queue <- outboundMessage{channelID: channelID, replyTo: replyTo, text: part, queuedAt: time.Now()}
+	}
+	telemetry.RecordMessageQueueDepth(context.Background(), int64(len(a.queue)))
+	return nil
+}
+
+// retryDelay is the backoff before the given attempt, doubling each time
+func retryDelay(attempt int) time.Duration {
+	return sendBackoff << (attempt - 1)
+}
@@ -0,0 +1,231 @@
+// Package actions handles Twitch chat commands and actions
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
+	"github.com/mvaldes14/twitch-bot/pkgs/locale"
+)
+
+var errCommandExists = errors.New("command name is already registered")
+
+// CommandHandler runs a command that needs more than a fixed response.
+// Returning an error wrapping ErrUsage sends the reason and the command usage to chat.
+type CommandHandler func(ctx context.Context, inv Invocation) error
+
+// Command is a chat command the bot answers
+type Command struct {
+	Name    string
+	Aliases []string
+	// Response is sent to chat when there is no Handler, see renderResponse for its variables.
+	// Responses replaces it while the invocation locale is one of its keys
+	Response  string
+	Responses map[string]string
+	Handler   CommandHandler
+	// Description and Usage document the command, Hidden keeps it out of !commands.
+	// Usage lists the arguments only, e.g. "<title>"
+	Description string
+	Usage       string
+	Hidden      bool
+	// MinArgs is the number of arguments required before the handler runs
+	MinArgs int
+	// NoReply answers with a regular message instead of a reply to the invoking message
+	NoReply bool
+	// Permission is the lowest role allowed to run the command
+	Permission Permission
+}
+
+// Registry holds the commands of a channel, looked up by name or alias regardless of case
+type Registry struct {
+	prefix   string
+	commands map[string]*Command
+	lookup   map[string]*Command
+}
+
+// NewRegistry creates an empty command registry for commands starting with prefix
+func NewRegistry(prefix string) *Registry {
+	return &Registry{
+		prefix:   prefix,
+		commands: map[string]*Command{},
+		lookup:   map[string]*Command{},
+	}
+}
+
+// Register adds a command, failing if its name or any alias is already taken
+func (r *Registry) Register(cmd Command) error {
+	cmd.Name = strings.ToLower(cmd.Name)
+	names := []string{cmd.Name}
+	for _, alias := range cmd.Aliases {
+		names = append(names, strings.ToLower(alias))
+	}
+	for _, name := range names {
+		if _, taken := r.lookup[name]; taken {
+			return fmt.Errorf("%w: %s%s", errCommandExists, r.prefix, name)
+		}
+	}
+	registered := &cmd
+	r.commands[cmd.Name] = registered
+	for _, name := range names {
+		r.lookup[name] = registered
+	}
+	return nil
+}
+
+// Lookup finds a command by name or alias, without the prefix
+func (r *Registry) Lookup(name string) (*Command, bool) {
+	cmd, ok := r.lookup[strings.ToLower(name)]
+	return cmd, ok
+}
+
+// Commands returns every registered command sorted by name
+func (r *Registry) Commands() []*Command {
+	commands := make([]*Command, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		commands = append(commands, cmd)
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+	return commands
+}
+
+// Help lists the visible commands together with extra command names, e.g. "!blog, !commands, !song"
+func (r *Registry) Help(extra ...string) string {
+	var names []string
+	for _, cmd := range r.Commands() {
+		if !cmd.Hidden {
+			names = append(names, r.prefix+cmd.Name)
+		}
+	}
+	for _, name := range extra {
+		names = append(names, r.prefix+name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// renderResponse fills the variables of a command response: {user} is the chatter's display name,
+// {args} the text after the command, {count} how many times the command was used and {uptime}
+// how long the channel has been live. The counter only moves for responses that show it.
+func (a *Actions) renderResponse(cmd *Command, inv Invocation) string {
+	channelID := inv.ChannelID
+	response := cmd.Response
+	if localized, ok := cmd.Responses[inv.Locale]; ok {
+		response = localized
+	}
+	replacements := []string{
+		"{user}", inv.ChatterName,
+		"{args}", inv.RawArgs,
+	}
+	if strings.Contains(response, "{count}") {
+		count, err := a.Cache.IncrementField(commandCountsKey(channelID), cmd.Name)
+		if err != nil {
+			a.Log.Error("Failed to increment usage count of !"+cmd.Name, err)
+		}
+		replacements = append(replacements, "{count}", strconv.FormatInt(count, 10))
+	}
+	if strings.Contains(response, "{uptime}") {
+		uptime := "offline"
+		if live, ok := a.Uptime(channelID); ok {
+			uptime = formatUptime(live)
+		}
+		replacements = append(replacements, "{uptime}", uptime)
+	}
+	return strings.NewReplacer(replacements...).Replace(response)
+}
+
+// builtinCommands are the commands implemented in code, available in every channel
+func (a *Actions) builtinCommands(registry *Registry) []Command {
+	commands := []Command{
+		{
+			Name:        "commands",
+			Description: "Lists available commands",
+			Handler: func(_ context.Context, inv Invocation) error {
+				var custom []string
+				commands, err := a.ListCustomCommands(inv.ChannelID)
+				if err != nil {
+					a.Log.Error("Failed to list custom commands for !commands", err)
+				}
+				for _, cmd := range commands {
+					custom = append(custom, cmd.Name)
+				}
+				return a.Respond(inv, registry.Help(custom...))
+			},
+		},
+		{
+			Name:        "song",
+			Description: "Shows the song playing on Spotify",
+			Handler:     a.currentSong,
+		},
+		{
+			Name:        "clip",
+			Description: "Clips the last seconds of the stream",
+			Handler:     a.clip,
+		},
+		{
+			Name:        "lang",
+			Description: "Shows or switches the language of the bot for this stream",
+			Usage:       "[locale]",
+			Permission:  PermissionModerator,
+			Handler:     a.switchLocale,
+		},
+	}
+	commands = append(commands, a.channelInfoCommands()...)
+	commands = append(commands, a.moderatorCommands()...)
+	commands = append(commands, a.pollCommands()...)
+	if moderation := a.Config.Moderation; moderation.Enabled && moderation.Links.Enabled {
+		commands = append(commands, Command{
+			Name:        "permit",
+			Description: "Lets a chatter post links for a short time",
+			Usage:       "<user>",
+			Permission:  PermissionModerator,
+			MinArgs:     1,
+			Handler:     a.permitLinks,
+		})
+	}
+	return commands
+}
+
+// newChannelRegistry builds the registry of a channel from the built-in commands and its configured commands
+func (a *Actions) newChannelRegistry(channel config.Channel) *Registry {
+	registry := NewRegistry(a.Config.CommandPrefix)
+	for _, cmd := range a.builtinCommands(registry) {
+		if err := registry.Register(cmd); err != nil {
+			a.Log.Error("Failed to register built-in command", err)
+		}
+	}
+	for _, cmd := range a.Config.ChannelCommands(channel) {
+		permission, err := ParsePermission(cmd.Permission)
+		if err != nil {
+			a.Log.Error(fmt.Sprintf("Skipping configured command for channel %s", channel.ID), err)
+			continue
+		}
+		err = registry.Register(Command{
+			Name:        cmd.Name,
+			Aliases:     cmd.Aliases,
+			Response:    cmd.Response,
+			Responses:   localizedResponses(cmd.Responses),
+			Description: cmd.Description,
+			Hidden:      cmd.Hidden,
+			Permission:  permission,
+			NoReply:     cmd.Reply != nil && !*cmd.Reply,
+		})
+		if err != nil {
+			a.Log.Error(fmt.Sprintf("Skipping configured command for channel %s", channel.ID), err)
+		}
+	}
+	return registry
+}
+
+// localizedResponses keys configured per-locale responses by normalized locale
+func localizedResponses(responses map[string]string) map[string]string {
+	localized := make(map[string]string, len(responses))
+	for loc, response := range responses {
+		localized[locale.Normalize(loc)] = response
+	}
+	return localized
+}
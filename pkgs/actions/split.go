@@ -0,0 +1,77 @@
+// Package actions handles Twitch chat commands and actions
+package actions
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxMessageLength is the longest chat message Helix accepts, in characters
+const maxMessageLength = 500
+
+// splitMessage breaks text into parts of at most limit characters on word boundaries,
+// numbering them as "(1/3) ..." when more than one part is needed. Words longer than a part are cut.
+// Empty or whitespace-only text has no parts.
+func splitMessage(text string, limit int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	if utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	// Leave room for the "(n/total) " label, sized for the worst case of the part count
+	words := strings.Fields(text)
+	var parts []string
+	for reserve := len("(1/9) "); ; reserve++ {
+		parts = wrapWords(words, limit-reserve)
+		if len(fmt.Sprintf("(%d/%d) ", len(parts), len(parts))) <= reserve {
+			break
+		}
+	}
+	for i, part := range parts {
+		parts[i] = fmt.Sprintf("(%d/%d) %s", i+1, len(parts), part)
+	}
+	return parts
+}
+
+// wrapWords joins words into lines of at most width characters
+func wrapWords(words []string, width int) []string {
+	var (
+		lines   []string
+		current strings.Builder
+		length  int
+	)
+	flush := func() {
+		if length > 0 {
+			lines = append(lines, current.String())
+			current.Reset()
+			length = 0
+		}
+	}
+	for _, word := range words {
+		runes := []rune(word)
+		// Words that cannot fit on any line are cut into width sized pieces
+		for len(runes) > width {
+			flush()
+			lines = append(lines, string(runes[:width]))
+			runes = runes[width:]
+		}
+		if len(runes) == 0 {
+			continue
+		}
+		if length > 0 && length+1+len(runes) > width {
+			flush()
+		}
+		if length > 0 {
+			current.WriteByte(' ')
+			length++
+		}
+		current.WriteString(string(runes))
+		length += len(runes)
+	}
+	flush()
+	return lines
+}
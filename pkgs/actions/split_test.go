@@ -0,0 +1,90 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/telemetry"
+)
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{name: "fits", text: "  hello chat  ", limit: 20, want: []string{"hello chat"}},
+		{name: "empty", text: "", limit: 20},
+		{name: "only whitespace", text: " \t\n ", limit: 20},
+		{name: "exactly the limit", text: "abcde", limit: 5, want: []string{"abcde"}},
+		{name: "counts characters not bytes", text: "ñññññ", limit: 5, want: []string{"ñññññ"}},
+		{name: "split on words", text: "one two three four five", limit: 15, want: []string{"(1/3) one two", "(2/3) three", "(3/3) four five"}},
+		{name: "long word is cut", text: "abcdefghijklmnopqrstuvwxyz", limit: 15, want: []string{"(1/3) abcdefghi", "(2/3) jklmnopqr", "(3/3) stuvwxyz"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitMessage(tt.text, tt.limit)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitMessage(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitMessageStaysWithinLimit(t *testing.T) {
+	// Enough parts that the label grows to "(10/12) "
+	text := strings.Repeat("palabra ", 700)
+	parts := splitMessage(text, maxMessageLength)
+	if len(parts) < 10 {
+		t.Fatalf("got %d parts, want at least 10", len(parts))
+	}
+	var words []string
+	for i, part := range parts {
+		if n := utf8.RuneCountInString(part); n > maxMessageLength {
+			t.Errorf("part %d has %d characters", i+1, n)
+		}
+		label := fmt.Sprintf("(%d/%d) ", i+1, len(parts))
+		if !strings.HasPrefix(part, label) {
+			t.Errorf("part %d = %q, want the label %q", i+1, part[:12], label)
+		}
+		words = append(words, strings.Fields(strings.TrimPrefix(part, label))...)
+	}
+	if strings.Join(words, " ") != strings.TrimSpace(text) {
+		t.Error("the parts do not add up to the message")
+	}
+}
+
+func TestReplyQueuesAllPartsOrNone(t *testing.T) {
+	a := &Actions{Log: telemetry.NewLogger("actions-test"), queue: make(chan outboundMessage, 3)}
+	long := strings.Repeat("palabra ", 150) // 3 parts
+
+	if err := a.SendMessage("100", "first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Reply("100", "parent", long); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Reply error = %v, want %v with room for only 2 of 3 parts", err, ErrQueueFull)
+	}
+	if len(a.queue) != 1 {
+		t.Fatalf("queue holds %d messages, want only the first one", len(a.queue))
+	}
+
+	if err := a.SendMessage("100", "   "); err != nil || len(a.queue) != 1 {
+		t.Fatalf("SendMessage of blank text = %v and queued %d messages, want nothing queued", err, len(a.queue)-1)
+	}
+
+	<-a.queue
+	if err := a.Reply("100", "parent", long); err != nil {
+		t.Fatalf("Reply error = %v with room for every part", err)
+	}
+	for i := 1; i <= 3; i++ {
+		msg := <-a.queue
+		if msg.replyTo != "parent" || !strings.HasPrefix(msg.text, fmt.Sprintf("(%d/3) ", i)) {
+			t.Errorf("part %d = %q replying to %q", i, msg.text[:8], msg.replyTo)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Package actions handles Twitch chat commands and actions
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/subscriptions"
+	"github.com/redis/go-redis/v9"
+)
+
+// liveStreamsKey is the Redis hash of the channels that are live, keyed by broadcaster ID with the start as unix
+// seconds. Keeping it in Redis lets the uptime and timers survive a restart and work on every replica.
+const liveStreamsKey = "streams:live"
+
+// StreamOnline records that a channel went live at the given time, arms its timers and starts a new clip list
+func (a *Actions) StreamOnline(channelID string, at time.Time) {
+	a.recordStreamStart(channelID, at)
+	a.armTimers(channelID)
+	a.resetClips(channelID)
+}
+
+// recordStreamStart marks a channel as live since at
+func (a *Actions) recordStreamStart(channelID string, at time.Time) {
+	if err := a.Cache.SetField(liveStreamsKey, channelID, strconv.FormatInt(at.Unix(), 10)); err != nil {
+		a.Log.Error("Failed to record stream start of channel "+channelID, err)
+	}
+}
+
+// StreamOffline clears the live state and session locale of a channel and returns when it had started
+func (a *Actions) StreamOffline(channelID string) (time.Time, bool) {
+	a.resetLocale(channelID)
+	start, live := a.streamStart(channelID)
+	if _, err := a.Cache.DeleteField(liveStreamsKey, channelID); err != nil {
+		a.Log.Error("Failed to clear stream start of channel "+channelID, err)
+	}
+	return start, live
+}
+
+// Uptime returns how long a channel has been live, false when it is offline
+func (a *Actions) Uptime(channelID string) (time.Duration, bool) {
+	start, live := a.streamStart(channelID)
+	if !live {
+		return 0, false
+	}
+	return time.Since(start), true
+}
+
+// streamStart reads when a channel went live, false when it is offline or the state could not be read
+func (a *Actions) streamStart(channelID string) (time.Time, bool) {
+	value, err := a.Cache.GetField(liveStreamsKey, channelID)
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, false
+	}
+	if err != nil {
+		a.Log.Error("Failed to read stream start of channel "+channelID, err)
+		return time.Time{}, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		a.Log.Error("Invalid stream start of channel "+channelID, err)
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
+// SyncStreams asks Twitch which channels are live and corrects the recorded state, for stream.online or
+// stream.offline notifications missed while the bot was down
+func (a *Actions) SyncStreams(ctx context.Context) {
+	for _, channel := range a.Config.AllChannels() {
+		query := url.Values{"user_id": {channel.ID}}
+		var streams subscriptions.StreamsResponse
+		if err := a.helixUserRequest(ctx, "get_streams", http.MethodGet, "/streams?"+query.Encode(), channel.ID, nil, &streams); err != nil {
+			a.Log.Error("Failed to check if channel "+channel.ID+" is live", err)
+			continue
+		}
+		_, recorded := a.streamStart(channel.ID)
+		switch {
+		case len(streams.Data) > 0 && !recorded:
+			a.Log.Info(fmt.Sprintf("[STREAM: LIVE] Channel %s has been live since %s", channel.ID, streams.Data[0].StartedAt.Format(time.RFC3339)))
+			a.recordStreamStart(channel.ID, streams.Data[0].StartedAt)
+		case len(streams.Data) == 0 && recorded:
+			a.Log.Info(fmt.Sprintf("[STREAM: OFFLINE] Channel %s went offline while the bot was down", channel.ID))
+			if _, err := a.Cache.DeleteField(liveStreamsKey, channel.ID); err != nil {
+				a.Log.Error("Failed to clear stream start of channel "+channel.ID, err)
+			}
+		}
+	}
+}
+
+// formatUptime renders an uptime as "1h 05m" for chat
+func formatUptime(uptime time.Duration) string {
+	uptime = uptime.Round(time.Minute)
+	return fmt.Sprintf("%dh %02dm", int(uptime.Hours()), int(uptime.Minutes())%60)
+}
@@ -0,0 +1,268 @@
+// Package actions handles Twitch chat commands and actions
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// timerTick is how often live channels are checked for timers that are due
+const timerTick = 30 * time.Second
+
+const (
+	// TimerSourceConfig marks timers defined in the config file, they cannot be changed through the API
+	TimerSourceConfig = "config"
+	// TimerSourceAPI marks timers added at runtime and stored in Redis
+	TimerSourceAPI = "api"
+)
+
+var (
+	// ErrTimerNotFound is returned when a channel has no runtime timer with the given name
+	ErrTimerNotFound = errors.New("timer not found")
+	// ErrTimerConflict is returned when a timer name is taken or belongs to a configured timer
+	ErrTimerConflict = errors.New("timer already exists")
+	// ErrInvalidTimer is returned when a timer has an unusable name, message or schedule
+	ErrInvalidTimer = errors.New("invalid timer")
+)
+
+// Timer is a message repeated in chat while the channel is live
+type Timer struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	// Interval is a Go duration such as "15m", at least config.MinTimerInterval
+	Interval string `json:"interval"`
+	// MinMessages is the number of chat messages required since the timer was last sent
+	MinMessages int    `json:"min_messages"`
+	Disabled    bool   `json:"disabled"`
+	Source      string `json:"source"`
+}
+
+// timersKey is the Redis hash holding a channel's runtime timers, one field per name
+func timersKey(channelID string) string {
+	return "timers:" + channelID
+}
+
+// timerStateKey is the Redis hash counting a channel's chat messages and the count when each timer was last sent
+func timerStateKey(channelID string) string {
+	return "timers:" + channelID + ":state"
+}
+
+// timerWindowKey marks a running timer interval, shared between replicas
+func timerWindowKey(channelID, name string) string {
+	return "timers:" + channelID + ":window:" + name
+}
+
+// ListTimers returns the configured and runtime timers of a channel sorted by name
+func (a *Actions) ListTimers(channelID string) ([]Timer, error) {
+	channel, ok := a.Config.Channel(channelID)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown channel %s", ErrInvalidTimer, channelID)
+	}
+	var timers []Timer
+	configured := map[string]bool{}
+	for _, timer := range a.Config.ChannelTimers(channel) {
+		configured[timer.Name] = true
+		timers = append(timers, Timer{
+			Name:        timer.Name,
+			Message:     timer.Message,
+			Interval:    timer.Interval.String(),
+			MinMessages: timer.MinMessages,
+			Source:      TimerSourceConfig,
+		})
+	}
+
+	fields, err := a.Cache.GetFields(timersKey(channelID))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range fields {
+		// A configured timer added after the runtime one takes its place
+		if configured[name] {
+			continue
+		}
+		var timer Timer
+		if err := json.Unmarshal([]byte(value), &timer); err != nil {
+			a.Log.Error("Skipping unreadable runtime timer "+name, err)
+			continue
+		}
+		timer.Source = TimerSourceAPI
+		timers = append(timers, timer)
+	}
+	sort.Slice(timers, func(i, j int) bool { return timers[i].Name < timers[j].Name })
+	return timers, nil
+}
+
+// CreateTimer adds a runtime timer, failing if a timer with the same name exists
+func (a *Actions) CreateTimer(channelID string, timer Timer) (Timer, error) {
+	if err := a.validateTimer(channelID, &timer); err != nil {
+		return timer, err
+	}
+	if _, err := a.Cache.GetField(timersKey(channelID), timer.Name); err == nil {
+		return timer, fmt.Errorf("%w: %s", ErrTimerConflict, timer.Name)
+	} else if !errors.Is(err, redis.Nil) {
+		return timer, err
+	}
+	return timer, a.storeTimer(channelID, timer)
+}
+
+// UpdateTimer replaces an existing runtime timer
+func (a *Actions) UpdateTimer(channelID string, timer Timer) (Timer, error) {
+	if err := a.validateTimer(channelID, &timer); err != nil {
+		return timer, err
+	}
+	if _, err := a.Cache.GetField(timersKey(channelID), timer.Name); errors.Is(err, redis.Nil) {
+		return timer, ErrTimerNotFound
+	} else if err != nil {
+		return timer, err
+	}
+	return timer, a.storeTimer(channelID, timer)
+}
+
+// DeleteTimer removes a runtime timer
+func (a *Actions) DeleteTimer(channelID, name string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	removed, err := a.Cache.DeleteField(timersKey(channelID), name)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrTimerNotFound
+	}
+	return nil
+}
+
+func (a *Actions) storeTimer(channelID string, timer Timer) error {
+	timer.Source = ""
+	value, err := json.Marshal(timer)
+	if err != nil {
+		return err
+	}
+	return a.Cache.SetField(timersKey(channelID), timer.Name, string(value))
+}
+
+// validateTimer normalizes a runtime timer and rejects names used by configured timers
+func (a *Actions) validateTimer(channelID string, timer *Timer) error {
+	channel, ok := a.Config.Channel(channelID)
+	if !ok {
+		return fmt.Errorf("%w: unknown channel %s", ErrInvalidTimer, channelID)
+	}
+	timer.Name = strings.ToLower(strings.TrimSpace(timer.Name))
+	timer.Message = strings.TrimSpace(timer.Message)
+	timer.Source = TimerSourceAPI
+	if !config.ValidCommandName(timer.Name) {
+		return fmt.Errorf("%w: name %q must be a single word", ErrInvalidTimer, timer.Name)
+	}
+	if timer.Message == "" || len(timer.Message) > maxResponseLength {
+		return fmt.Errorf("%w: message must be 1-%d characters", ErrInvalidTimer, maxResponseLength)
+	}
+	interval, err := time.ParseDuration(timer.Interval)
+	if err != nil || interval < config.MinTimerInterval {
+		return fmt.Errorf("%w: interval must be a duration of at least %s", ErrInvalidTimer, config.MinTimerInterval)
+	}
+	timer.Interval = interval.String()
+	if timer.MinMessages < 0 {
+		return fmt.Errorf("%w: min_messages must not be negative", ErrInvalidTimer)
+	}
+	for _, configured := range a.Config.ChannelTimers(channel) {
+		if configured.Name == timer.Name {
+			return fmt.Errorf("%w: %s is defined in the config file", ErrTimerConflict, timer.Name)
+		}
+	}
+	return nil
+}
+
+// countChatMessage counts a chat message towards the min_messages of the channel's timers
+func (a *Actions) countChatMessage(channelID string) {
+	if _, err := a.Cache.IncrementField(timerStateKey(channelID), "messages"); err != nil {
+		a.Log.Error("Failed to count chat message for timers", err)
+	}
+}
+
+// armTimers starts the interval of every timer when a channel goes live, so none fires right away
+func (a *Actions) armTimers(channelID string) {
+	timers, err := a.ListTimers(channelID)
+	if err != nil {
+		a.Log.Error("Failed to arm timers for channel "+channelID, err)
+		return
+	}
+	for _, timer := range timers {
+		interval, err := time.ParseDuration(timer.Interval)
+		if err != nil || timer.Disabled {
+			continue
+		}
+		if _, err := a.Cache.SetIfAbsent(timerWindowKey(channelID, timer.Name), interval); err != nil {
+			a.Log.Error("Failed to arm timer "+timer.Name, err)
+		}
+	}
+}
+
+// StartTimers sends due timers to every live channel until ctx is cancelled
+func (a *Actions) StartTimers(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(timerTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, channel := range a.Config.AllChannels() {
+					if _, live := a.Uptime(channel.ID); live {
+						a.runTimers(channel.ID)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// runTimers sends the timers of a channel whose interval elapsed and that saw enough chat messages.
+// The interval window is taken last so a quiet chat delays the timer instead of skipping a whole interval.
+func (a *Actions) runTimers(channelID string) {
+	timers, err := a.ListTimers(channelID)
+	if err != nil {
+		a.Log.Error("Failed to load timers for channel "+channelID, err)
+		return
+	}
+	if len(timers) == 0 {
+		return
+	}
+	state, err := a.Cache.GetFields(timerStateKey(channelID))
+	if err != nil {
+		a.Log.Error("Failed to load timer state for channel "+channelID, err)
+		return
+	}
+	messages, _ := strconv.ParseInt(state["messages"], 10, 64)
+
+	for _, timer := range timers {
+		interval, err := time.ParseDuration(timer.Interval)
+		if err != nil || timer.Disabled {
+			continue
+		}
+		lastSent, _ := strconv.ParseInt(state["sent:"+timer.Name], 10, 64)
+		if messages-lastSent < int64(timer.MinMessages) {
+			continue
+		}
+		due, err := a.Cache.SetIfAbsent(timerWindowKey(channelID, timer.Name), interval)
+		if err != nil || !due {
+			continue
+		}
+
+		a.Log.Info(fmt.Sprintf("[TIMER: %s] Announcing in channel %s", timer.Name, channelID))
+		if err := a.SendMessage(channelID, timer.Message); err != nil {
+			continue
+		}
+		if err := a.Cache.SetField(timerStateKey(channelID), "sent:"+timer.Name, strconv.FormatInt(messages, 10)); err != nil {
+			a.Log.Error("Failed to store timer state for "+timer.Name, err)
+		}
+	}
+}
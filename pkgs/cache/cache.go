@@ -131,3 +131,240 @@ func (c *Service) DeleteToken(key string) error {
 	c.Log.Info(fmt.Sprintf("[CACHE DELETE] ✓ Token '%s' deleted successfully from Redis", key))
 	return nil
 }
+
+// SetIfAbsent stores a marker key only when it does not already exist.
+// Returns true when the key was created and false when it was already present.
+func (c *Service) SetIfAbsent(key string, expiration time.Duration) (bool, error) {
+	_, span := telemetry.StartSpan(ctx, "redis.set_if_absent",
+		attribute.String("cache.key", key),
+		attribute.Int64("cache.expiration_seconds", int64(expiration.Seconds())),
+	)
+	defer span.End()
+
+	created, err := rdb.SetNX(ctx, key, time.Now().Unix(), expiration).Result()
+	if err != nil {
+		c.Log.Error(fmt.Sprintf("Failed to set key '%s' in Redis: %v", key, err), err)
+		telemetry.RecordError(span, err)
+		telemetry.IncrementCacheOperation(ctx, "set_if_absent", "error")
+		return false, err
+	}
+	if created {
+		telemetry.IncrementCacheOperation(ctx, "set_if_absent", "created")
+	} else {
+		telemetry.IncrementCacheOperation(ctx, "set_if_absent", "exists")
+	}
+	return created, nil
+}
+
+// SetMarker stores a marker key, replacing an existing one so its expiration starts over
+func (c *Service) SetMarker(key string, expiration time.Duration) error {
+	_, span := telemetry.StartSpan(ctx, "redis.set_marker",
+		attribute.String("cache.key", key),
+		attribute.Int64("cache.expiration_seconds", int64(expiration.Seconds())),
+	)
+	defer span.End()
+
+	if err := rdb.Set(ctx, key, time.Now().Unix(), expiration).Err(); err != nil {
+		c.Log.Error(fmt.Sprintf("Failed to set key '%s' in Redis: %v", key, err), err)
+		telemetry.RecordError(span, err)
+		telemetry.IncrementCacheOperation(ctx, "set_marker", "error")
+		return err
+	}
+	telemetry.IncrementCacheOperation(ctx, "set_marker", "success")
+	return nil
+}
+
+// SetField stores a value under a field of a Redis hash
+func (c *Service) SetField(key, field, value string) error {
+	_, span := telemetry.StartSpan(ctx, "redis.set_field",
+		attribute.String("cache.key", key),
+		attribute.String("cache.field", field),
+	)
+	defer span.End()
+
+	if err := rdb.HSet(ctx, key, field, value).Err(); err != nil {
+		c.Log.Error(fmt.Sprintf("Failed to set field '%s' of '%s' in Redis: %v", field, key, err), err)
+		telemetry.RecordError(span, err)
+		telemetry.IncrementCacheOperation(ctx, "set_field", "error")
+		return err
+	}
+	telemetry.IncrementCacheOperation(ctx, "set_field", "success")
+	return nil
+}
+
+// GetField reads a field of a Redis hash, returning redis.Nil when it does not exist
+func (c *Service) GetField(key, field string) (string, error) {
+	_, span := telemetry.StartSpan(ctx, "redis.get_field",
+		attribute.String("cache.key", key),
+		attribute.String("cache.field", field),
+	)
+	defer span.End()
+
+	val, err := rdb.HGet(ctx, key, field).Result()
+	if errors.Is(err, redis.Nil) {
+		telemetry.IncrementCacheOperation(ctx, "get_field", "miss")
+		return "", err
+	}
+	if err != nil {
+		c.Log.Error(fmt.Sprintf("Failed to get field '%s' of '%s' from Redis: %v", field, key, err), err)
+		telemetry.RecordError(span, err)
+		telemetry.IncrementCacheOperation(ctx, "get_field", "error")
+		return "", err
+	}
+	telemetry.IncrementCacheOperation(ctx, "get_field", "hit")
+	return val, nil
+}
+
+// GetFields reads every field of a Redis hash, a missing hash is returned as an empty map
+func (c *Service) GetFields(key string) (map[string]string, error) {
+	_, span := telemetry.StartSpan(ctx, "redis.get_fields",
+		attribute.String("cache.key", key),
+	)
+	defer span.End()
+
+	fields, err := rdb.HGetAll(ctx, key).Result()
+	if err != nil {
+		c.Log.Error(fmt.Sprintf("Failed to get fields of '%s' from Redis: %v", key, err), err)
+		telemetry.RecordError(span, err)
+		telemetry.IncrementCacheOperation(ctx, "get_fields", "error")
+		return nil, err
+	}
+	telemetry.IncrementCacheOperation(ctx, "get_fields", "success")
+	return fields, nil
+}
+
+// DeleteField removes a field of a Redis hash and reports whether it existed
+func (c *Service) DeleteField(key, field string) (bool, error) {
+	_, span := telemetry.StartSpan(ctx, "redis.delete_field",
+		attribute.String("cache.key", key),
+		attribute.String("cache.field", field),
+	)
+	defer span.End()
+
+	removed, err := rdb.HDel(ctx, key, field).Result()
+	if err != nil {
+		c.Log.Error(fmt.Sprintf("Failed to delete field '%s' of '%s' from Redis: %v", field, key, err), err)
+		telemetry.RecordError(span, err)
+		telemetry.IncrementCacheOperation(ctx, "delete_field", "error")
+		return false, err
+	}
+	telemetry.IncrementCacheOperation(ctx, "delete_field", "success")
+	return removed > 0, nil
+}
+
+// IncrementField adds one to a counter stored in a field of a Redis hash and returns the new value
+func (c *Service) IncrementField(key, field string) (int64, error) {
+	_, span := telemetry.StartSpan(ctx, "redis.increment_field",
+		attribute.String("cache.key", key),
+		attribute.String("cache.field", field),
+	)
+	defer span.End()
+
+	value, err := rdb.HIncrBy(ctx, key, field, 1).Result()
+	if err != nil {
+		c.Log.Error(fmt.Sprintf("Failed to increment field '%s' of '%s' in Redis: %v", field, key, err), err)
+		telemetry.RecordError(span, err)
+		telemetry.IncrementCacheOperation(ctx, "increment_field", "error")
+		return 0, err
+	}
+	telemetry.IncrementCacheOperation(ctx, "increment_field", "success")
+	return value, nil
+}
+
+// Exists reports whether a key is present in Redis
+func (c *Service) Exists(key string) (bool, error) {
+	_, span := telemetry.StartSpan(ctx, "redis.exists",
+		attribute.String("cache.key", key),
+	)
+	defer span.End()
+
+	count, err := rdb.Exists(ctx, key).Result()
+	if err != nil {
+		c.Log.Error(fmt.Sprintf("Failed to check key '%s' in Redis: %v", key, err), err)
+		telemetry.RecordError(span, err)
+		telemetry.IncrementCacheOperation(ctx, "exists", "error")
+		return false, err
+	}
+	telemetry.IncrementCacheOperation(ctx, "exists", "success")
+	return count > 0, nil
+}
+
+// IncrementWithExpiry adds one to a counter key and returns the new value.
+// The expiration starts with the first increment, so the counter counts within a fixed window
+func (c *Service) IncrementWithExpiry(key string, expiration time.Duration) (int64, error) {
+	_, span := telemetry.StartSpan(ctx, "redis.increment_with_expiry",
+		attribute.String("cache.key", key),
+		attribute.Int64("cache.expiration_seconds", int64(expiration.Seconds())),
+	)
+	defer span.End()
+
+	value, err := rdb.Incr(ctx, key).Result()
+	if err == nil && value == 1 {
+		err = rdb.Expire(ctx, key, expiration).Err()
+	}
+	if err != nil {
+		c.Log.Error(fmt.Sprintf("Failed to increment '%s' in Redis: %v", key, err), err)
+		telemetry.RecordError(span, err)
+		telemetry.IncrementCacheOperation(ctx, "increment_with_expiry", "error")
+		return 0, err
+	}
+	telemetry.IncrementCacheOperation(ctx, "increment_with_expiry", "success")
+	return value, nil
+}
+
+// PushCapped adds a value to the front of a Redis list and trims the list to its newest size entries
+func (c *Service) PushCapped(key, value string, size int64) error {
+	_, span := telemetry.StartSpan(ctx, "redis.push_capped",
+		attribute.String("cache.key", key),
+	)
+	defer span.End()
+
+	_, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, key, value)
+		pipe.LTrim(ctx, key, 0, size-1)
+		return nil
+	})
+	if err != nil {
+		c.Log.Error(fmt.Sprintf("Failed to push to '%s' in Redis: %v", key, err), err)
+		telemetry.RecordError(span, err)
+		telemetry.IncrementCacheOperation(ctx, "push_capped", "error")
+		return err
+	}
+	telemetry.IncrementCacheOperation(ctx, "push_capped", "success")
+	return nil
+}
+
+// GetList reads the first count entries of a Redis list, newest first for lists filled by PushCapped
+func (c *Service) GetList(key string, count int64) ([]string, error) {
+	_, span := telemetry.StartSpan(ctx, "redis.get_list",
+		attribute.String("cache.key", key),
+	)
+	defer span.End()
+
+	values, err := rdb.LRange(ctx, key, 0, count-1).Result()
+	if err != nil {
+		c.Log.Error(fmt.Sprintf("Failed to read list '%s' from Redis: %v", key, err), err)
+		telemetry.RecordError(span, err)
+		telemetry.IncrementCacheOperation(ctx, "get_list", "error")
+		return nil, err
+	}
+	telemetry.IncrementCacheOperation(ctx, "get_list", "success")
+	return values, nil
+}
+
+// Delete removes a key of any type from Redis
+func (c *Service) Delete(key string) error {
+	_, span := telemetry.StartSpan(ctx, "redis.delete",
+		attribute.String("cache.key", key),
+	)
+	defer span.End()
+
+	if err := rdb.Del(ctx, key).Err(); err != nil {
+		c.Log.Error(fmt.Sprintf("Failed to delete '%s' from Redis: %v", key, err), err)
+		telemetry.RecordError(span, err)
+		telemetry.IncrementCacheOperation(ctx, "delete", "error")
+		return err
+	}
+	telemetry.IncrementCacheOperation(ctx, "delete", "success")
+	return nil
+}
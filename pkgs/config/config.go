@@ -0,0 +1,805 @@
+// Package config loads the bot configuration from a YAML file and environment overrides
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/locale"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultPath is used when CONFIG_FILE is not set
+	DefaultPath = "config.yaml"
+	pathEnv     = "CONFIG_FILE"
+
+	// TransportWebhook delivers EventSub events as signed HTTP callbacks
+	TransportWebhook = "webhook"
+	// TransportWebSocket delivers EventSub events over a websocket session
+	TransportWebSocket = "websocket"
+
+	defaultWebSocketURL      = "wss://eventsub.wss.twitch.tv/ws"
+	defaultReconcileInterval = 30 * time.Minute
+	defaultCommandPrefix     = "!"
+	defaultRefreshTokenEnv   = "TWITCH_REFRESH_TOKEN"
+	defaultUserTokenEnv      = "TWITCH_USER_TOKEN"
+	defaultBotRefreshEnv     = "TWITCH_BOT_REFRESH_TOKEN"
+	defaultBotUserTokenEnv   = "TWITCH_BOT_USER_TOKEN"
+	// MinTimerInterval keeps timed announcements from flooding chat
+	MinTimerInterval = time.Minute
+	// MaxTags and MaxTagLength are the Twitch limits on stream tags
+	MaxTags      = 10
+	MaxTagLength = 25
+
+	// Moderation actions, from mildest to harshest
+	ModerationDelete  = "delete"
+	ModerationTimeout = "timeout"
+	ModerationBan     = "ban"
+	// MaxTimeout is the longest timeout Twitch accepts
+	MaxTimeout = 14 * 24 * time.Hour
+
+	defaultStrikeWindow   = 24 * time.Hour
+	defaultPermitDuration = time.Minute
+)
+
+var errInvalidConfig = errors.New("invalid configuration")
+
+// PermissionLevels are the roles a command can require, from lowest to highest
+var PermissionLevels = []string{"everyone", "subscriber", "vip", "moderator", "broadcaster"}
+
+// Config is the full bot configuration
+type Config struct {
+	// Broadcaster is the primary channel, its tokens come from TWITCH_USER_TOKEN and TWITCH_REFRESH_TOKEN by default
+	Broadcaster Channel `yaml:"broadcaster"`
+	// Channels are served by the same instance in addition to the primary channel
+	Channels []Channel `yaml:"channels"`
+	// Bot is the account chat messages are sent as, the broadcaster of each channel when not set
+	Bot           Bot           `yaml:"bot"`
+	EventSub      EventSub      `yaml:"eventsub"`
+	Notifications Notifications `yaml:"notifications"`
+	// CommandPrefix is the character starting a chat command, "!" by default
+	CommandPrefix string `yaml:"command_prefix"`
+	// Commands are answered in every channel unless a channel defines a command with the same name
+	Commands  []Command `yaml:"commands"`
+	Cooldowns Cooldowns `yaml:"cooldowns"`
+	// Timers are announced in every channel unless a channel defines a timer with the same name
+	Timers []Timer `yaml:"timers"`
+	// Presets are available in every channel unless a channel defines a preset with the same name
+	Presets []Preset `yaml:"presets"`
+	Locale  Locale   `yaml:"locale"`
+	// Moderation filters chat messages before commands are parsed
+	Moderation Moderation `yaml:"moderation"`
+}
+
+// Channel identifies a broadcaster the bot serves and the channel info `!today` applies when no preset is active
+type Channel struct {
+	ID          string   `yaml:"id"`
+	Name        string   `yaml:"name"`
+	Language    string   `yaml:"language"`
+	TitlePrefix string   `yaml:"title_prefix"`
+	CategoryID  string   `yaml:"category_id"`
+	Tags        []string `yaml:"tags"`
+	// Locale overrides locale.default for the bot's messages in this channel
+	Locale string `yaml:"locale"`
+	// StreamLiveMessage overrides notifications.stream_live_message for this channel
+	StreamLiveMessage string `yaml:"stream_live_message"`
+	// RefreshTokenEnv and UserTokenEnv name the environment variables holding this channel's tokens
+	RefreshTokenEnv string `yaml:"refresh_token_env"`
+	UserTokenEnv    string `yaml:"user_token_env"`
+	// Commands only answered in this channel
+	Commands []Command `yaml:"commands"`
+	// Timers only announced in this channel
+	Timers []Timer `yaml:"timers"`
+	// Presets only available in this channel
+	Presets []Preset `yaml:"presets"`
+}
+
+// Bot is a separate Twitch account the bot chats as. It needs the user:write:chat scope
+// and its tokens come from the environment variables named here
+type Bot struct {
+	ID              string `yaml:"id"`
+	Login           string `yaml:"login"`
+	RefreshTokenEnv string `yaml:"refresh_token_env"`
+	UserTokenEnv    string `yaml:"user_token_env"`
+}
+
+// Enabled reports whether a bot account is configured
+func (b Bot) Enabled() bool {
+	return b.ID != ""
+}
+
+// Command is a chat command answered with a fixed response.
+// The response may use {user}, {args}, {count} and {uptime}, see actions.renderResponse.
+type Command struct {
+	Name        string   `yaml:"name"`
+	Aliases     []string `yaml:"aliases"`
+	Response    string   `yaml:"response"`
+	Description string   `yaml:"description"`
+	// Hidden commands work but are not listed by !commands
+	Hidden bool `yaml:"hidden"`
+	// Permission is the lowest role allowed to run the command, one of PermissionLevels. Defaults to everyone
+	Permission string `yaml:"permission"`
+	// Reply threads the response as a reply to the invoking message, true when not set
+	Reply *bool `yaml:"reply"`
+	// Responses replaces Response while the active locale of the channel is one of its keys
+	Responses map[string]string `yaml:"responses"`
+}
+
+// Timer is a message repeated in chat while the channel is live, every Interval
+// and only after MinMessages chat messages since it was last sent
+type Timer struct {
+	Name        string        `yaml:"name"`
+	Message     string        `yaml:"message"`
+	Interval    time.Duration `yaml:"interval"`
+	MinMessages int           `yaml:"min_messages"`
+}
+
+// Preset is a named set of channel info switched to with `!preset <name>`
+type Preset struct {
+	Name string `yaml:"name"`
+	// Title is the title template used by !preset, !title and !today, {title} is replaced with the text given to them.
+	// A title without {title} is fixed
+	Title string `yaml:"title"`
+	// CategoryID is the Twitch category, Category is a name resolved through the Helix category search when it is empty
+	CategoryID string   `yaml:"category_id"`
+	Category   string   `yaml:"category"`
+	Tags       []string `yaml:"tags"`
+	Language   string   `yaml:"language"`
+}
+
+// Cooldowns limits how often commands run, windows are tracked per channel in Redis
+type Cooldowns struct {
+	// Notice answers the first suppressed attempt of a window instead of dropping it silently
+	Notice bool `yaml:"notice"`
+	// Commands maps a command name (not an alias, any case) to its cooldowns, unlisted commands have none
+	Commands map[string]Cooldown `yaml:"commands"`
+}
+
+// Cooldown is the minimum time between two runs of a command in a channel (Global) and by the same chatter (User)
+type Cooldown struct {
+	Global time.Duration `yaml:"global"`
+	User   time.Duration `yaml:"user"`
+}
+
+// Locale selects the language of the bot's own messages, see the locale package for the catalog
+type Locale struct {
+	// Default is the locale of channels that set none, until a moderator switches it with !lang
+	Default string `yaml:"default"`
+	// Commands pins a command name (any case) to a locale regardless of the active one
+	Commands map[string]string `yaml:"commands"`
+	// Messages overrides catalog templates, keyed by locale then message key. New locales may be added here
+	Messages map[string]map[string]string `yaml:"messages"`
+}
+
+// Moderation filters chat messages and acts on the ones breaking a rule.
+// Each violation is a strike for the chatter, the n-th strike within StrikeWindow gets the n-th Escalation step
+type Moderation struct {
+	Enabled bool `yaml:"enabled"`
+	// Exempt is the lowest permission level skipped by every rule, moderator by default
+	Exempt string `yaml:"exempt"`
+	// Notice tells chat why a message was removed, at most once per rule every 30 seconds, using the moderation
+	// messages of the locale catalog
+	Notice       bool             `yaml:"notice"`
+	Blocklist    Blocklist        `yaml:"blocklist"`
+	Links        LinkFilter       `yaml:"links"`
+	Caps         CapsFilter       `yaml:"caps"`
+	Emotes       EmoteFilter      `yaml:"emotes"`
+	Repeats      RepeatFilter     `yaml:"repeats"`
+	Escalation   []ModerationStep `yaml:"escalation"`
+	StrikeWindow time.Duration    `yaml:"strike_window"`
+}
+
+// ModerationStep is an action taken on a message: delete, timeout for Duration or ban
+type ModerationStep struct {
+	Action   string        `yaml:"action"`
+	Duration time.Duration `yaml:"duration"`
+}
+
+// Blocklist matches phrases (case insensitive) and regular expressions
+type Blocklist struct {
+	Phrases  []string `yaml:"phrases"`
+	Patterns []string `yaml:"patterns"`
+	// Action replaces the escalation for this rule when set, e.g. an immediate ban
+	Action ModerationStep `yaml:"action"`
+}
+
+// LinkFilter removes links from chatters below Permission unless a moderator used !permit
+type LinkFilter struct {
+	Enabled bool `yaml:"enabled"`
+	// Permission is the lowest level allowed to post links, vip by default
+	Permission string `yaml:"permission"`
+	// AllowedDomains may be linked by anyone, subdomains included
+	AllowedDomains []string `yaml:"allowed_domains"`
+	// PermitDuration is how long !permit lets a chatter post links
+	PermitDuration time.Duration  `yaml:"permit_duration"`
+	Action         ModerationStep `yaml:"action"`
+}
+
+// CapsFilter flags messages with at least MinLength letters of which more than MaxRatio are upper case
+type CapsFilter struct {
+	MinLength int            `yaml:"min_length"`
+	MaxRatio  float64        `yaml:"max_ratio"`
+	Action    ModerationStep `yaml:"action"`
+}
+
+// EmoteFilter flags messages with more than Max emotes
+type EmoteFilter struct {
+	Max    int            `yaml:"max"`
+	Action ModerationStep `yaml:"action"`
+}
+
+// RepeatFilter flags a chatter sending the same message Count times within Window
+type RepeatFilter struct {
+	Count  int            `yaml:"count"`
+	Window time.Duration  `yaml:"window"`
+	Action ModerationStep `yaml:"action"`
+}
+
+// EventSub configures how subscriptions are created and delivered
+type EventSub struct {
+	Transport            string        `yaml:"transport"`
+	CallbackURL          string        `yaml:"callback_url"`
+	Secret               string        `yaml:"secret"`
+	WebSocketURL         string        `yaml:"websocket_url"`
+	RecreateOnRevocation bool          `yaml:"recreate_on_revocation"`
+	ReconcileInterval    time.Duration `yaml:"reconcile_interval"`
+}
+
+// Notifications configures the go-live announcements
+type Notifications struct {
+	GotifyURL         string `yaml:"gotify_url"`
+	StreamLiveWebhook string `yaml:"stream_live_webhook"`
+	StreamLiveMessage string `yaml:"stream_live_message"`
+}
+
+// Default returns the settings used when neither the file nor the environment set a value
+func Default() *Config {
+	return &Config{
+		Broadcaster: Channel{
+			Language:        "en",
+			RefreshTokenEnv: defaultRefreshTokenEnv,
+			UserTokenEnv:    defaultUserTokenEnv,
+		},
+		Bot: Bot{
+			RefreshTokenEnv: defaultBotRefreshEnv,
+			UserTokenEnv:    defaultBotUserTokenEnv,
+		},
+		EventSub: EventSub{
+			Transport:         TransportWebhook,
+			WebSocketURL:      defaultWebSocketURL,
+			ReconcileInterval: defaultReconcileInterval,
+		},
+		CommandPrefix: defaultCommandPrefix,
+		Locale: Locale{
+			Default: locale.Default,
+		},
+		Moderation: Moderation{
+			Exempt:       "moderator",
+			Links:        LinkFilter{Permission: "vip", PermitDuration: defaultPermitDuration},
+			StrikeWindow: defaultStrikeWindow,
+			Escalation: []ModerationStep{
+				{Action: ModerationDelete},
+				{Action: ModerationTimeout, Duration: time.Minute},
+				{Action: ModerationTimeout, Duration: 10 * time.Minute},
+			},
+		},
+	}
+}
+
+// Load reads the file at CONFIG_FILE (or config.yaml), applies environment overrides and validates the result.
+// A missing file is not an error as long as the environment provides the required values.
+func Load() (*Config, error) {
+	path := os.Getenv(pathEnv)
+	if path == "" {
+		path = DefaultPath
+	}
+
+	cfg := Default()
+	data, err := os.ReadFile(path) // #nosec G304 -- path comes from the operator
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist):
+		if os.Getenv(pathEnv) != "" {
+			return nil, fmt.Errorf("config file %s set in %s does not exist", path, pathEnv)
+		}
+	default:
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// AllChannels returns the primary channel followed by the additional channels
+func (c *Config) AllChannels() []Channel {
+	return append([]Channel{c.Broadcaster}, c.Channels...)
+}
+
+// Channel finds a served channel by broadcaster ID
+func (c *Config) Channel(id string) (Channel, bool) {
+	for _, channel := range c.AllChannels() {
+		if channel.ID == id {
+			return channel, true
+		}
+	}
+	return Channel{}, false
+}
+
+// Cooldown returns the cooldowns of a command, zero when it has none
+func (c *Config) Cooldown(name string) Cooldown {
+	for command, cooldown := range c.Cooldowns.Commands {
+		if strings.EqualFold(command, name) {
+			return cooldown
+		}
+	}
+	return Cooldown{}
+}
+
+// applyEnv overrides file values with environment variables when they are set
+func (c *Config) applyEnv() error {
+	overrides := map[string]*string{
+		"TWITCH_BROADCASTER_ID":  &c.Broadcaster.ID,
+		"TWITCH_BOT_ID":          &c.Bot.ID,
+		"EVENTSUB_TRANSPORT":     &c.EventSub.Transport,
+		"EVENTSUB_CALLBACK_URL":  &c.EventSub.CallbackURL,
+		"EVENTSUB_SECRET":        &c.EventSub.Secret,
+		"EVENTSUB_WEBSOCKET_URL": &c.EventSub.WebSocketURL,
+		"GOTIFY_URL":             &c.Notifications.GotifyURL,
+		"STREAM_LIVE_WEBHOOK":    &c.Notifications.StreamLiveWebhook,
+	}
+	for key, field := range overrides {
+		if value := os.Getenv(key); value != "" {
+			*field = value
+		}
+	}
+
+	if value := os.Getenv("EVENTSUB_RECREATE_ON_REVOCATION"); value != "" {
+		c.EventSub.RecreateOnRevocation = value == "true"
+	}
+	if value := os.Getenv("EVENTSUB_RECONCILE_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("EVENTSUB_RECONCILE_INTERVAL is not a valid duration: %w", err)
+		}
+		c.EventSub.ReconcileInterval = interval
+	}
+	return nil
+}
+
+// Validate checks every setting and reports all problems at once
+func (c *Config) Validate() error {
+	var problems []string
+
+	if !isNumeric(c.Broadcaster.ID) {
+		problems = append(problems, "broadcaster.id must be the numeric Twitch user ID (TWITCH_BROADCASTER_ID)")
+	}
+	problems = append(problems, c.Broadcaster.validate("broadcaster")...)
+	if prefix := []rune(c.CommandPrefix); len(prefix) != 1 || unicode.IsLetter(prefix[0]) || unicode.IsDigit(prefix[0]) || unicode.IsSpace(prefix[0]) {
+		problems = append(problems, "command_prefix must be a single symbol such as ! or ?")
+	}
+	problems = append(problems, validateCommands("commands", c.Commands)...)
+	problems = append(problems, validateTimers("timers", c.Timers)...)
+	problems = append(problems, validatePresets("presets", c.Presets)...)
+	for name, cooldown := range c.Cooldowns.Commands {
+		if !ValidCommandName(name) {
+			problems = append(problems, fmt.Sprintf("cooldowns.commands name %q must be a single word without the ! prefix", name))
+		}
+		if cooldown.Global < 0 || cooldown.User < 0 {
+			problems = append(problems, fmt.Sprintf("cooldowns.commands.%s durations must not be negative", name))
+		}
+	}
+	problems = append(problems, c.validateLocale()...)
+	problems = append(problems, c.Moderation.validate()...)
+	seen := map[string]bool{c.Broadcaster.ID: true}
+	for i, channel := range c.Channels {
+		field := fmt.Sprintf("channels[%d]", i)
+		if !isNumeric(channel.ID) {
+			problems = append(problems, field+".id must be a numeric Twitch user ID")
+		} else if seen[channel.ID] {
+			problems = append(problems, fmt.Sprintf("%s.id %s is configured more than once", field, channel.ID))
+		}
+		seen[channel.ID] = true
+		// Only the primary channel falls back to TWITCH_REFRESH_TOKEN, others need their own
+		if channel.RefreshTokenEnv == "" {
+			problems = append(problems, field+".refresh_token_env must name the environment variable holding the channel's refresh token")
+		}
+		problems = append(problems, channel.validate(field)...)
+	}
+
+	if c.Bot.Enabled() {
+		if !isNumeric(c.Bot.ID) {
+			problems = append(problems, "bot.id must be the numeric Twitch user ID of the bot account (TWITCH_BOT_ID)")
+		} else if seen[c.Bot.ID] {
+			problems = append(problems, "bot.id must be a separate account, leave it empty to chat as the broadcaster")
+		}
+		if c.Bot.RefreshTokenEnv == "" {
+			problems = append(problems, "bot.refresh_token_env must name the environment variable holding the bot's refresh token")
+		}
+	}
+
+	switch c.EventSub.Transport {
+	case TransportWebhook:
+		if !isURL(c.EventSub.CallbackURL, "https") {
+			problems = append(problems, "eventsub.callback_url must be an https URL when using the webhook transport (EVENTSUB_CALLBACK_URL)")
+		}
+		// Twitch requires the webhook secret to be between 10 and 100 characters
+		if len(c.EventSub.Secret) < 10 || len(c.EventSub.Secret) > 100 {
+			problems = append(problems, "eventsub.secret must be 10-100 characters when using the webhook transport (EVENTSUB_SECRET)")
+		}
+	case TransportWebSocket:
+		if !isURL(c.EventSub.WebSocketURL, "wss", "ws") {
+			problems = append(problems, "eventsub.websocket_url must be a ws:// or wss:// URL (EVENTSUB_WEBSOCKET_URL)")
+		}
+		// A websocket session is managed with a single user token
+		if len(c.Channels) > 0 {
+			problems = append(problems, "eventsub.transport websocket only supports the primary channel, use webhooks to serve several channels")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("eventsub.transport must be %q or %q, got %q", TransportWebhook, TransportWebSocket, c.EventSub.Transport))
+	}
+	if c.EventSub.ReconcileInterval <= 0 {
+		problems = append(problems, "eventsub.reconcile_interval must be a positive duration")
+	}
+
+	if c.Notifications.GotifyURL != "" && !isURL(c.Notifications.GotifyURL, "https", "http") {
+		problems = append(problems, "notifications.gotify_url must be an http(s) URL")
+	}
+	if c.Notifications.StreamLiveWebhook != "" && !isURL(c.Notifications.StreamLiveWebhook, "https", "http") {
+		problems = append(problems, "notifications.stream_live_webhook must be an http(s) URL")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", errInvalidConfig, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateLocale checks every configured locale has templates, built in or under locale.messages
+func (c *Config) validateLocale() []string {
+	var problems []string
+	check := func(field, loc string) {
+		if !c.SupportedLocale(loc) {
+			problems = append(problems, fmt.Sprintf("%s %q is not a known locale, add its messages under locale.messages", field, loc))
+		}
+	}
+	check("locale.default", c.Locale.Default)
+	for name, loc := range c.Locale.Commands {
+		if !ValidCommandName(name) {
+			problems = append(problems, fmt.Sprintf("locale.commands name %q must be a single word without the ! prefix", name))
+		}
+		check("locale.commands."+name, loc)
+	}
+	for loc, messages := range c.Locale.Messages {
+		for key := range messages {
+			if !locale.KnownKey(key) {
+				problems = append(problems, fmt.Sprintf("locale.messages.%s.%s is not a message key", loc, key))
+			}
+		}
+	}
+	for i, channel := range c.AllChannels() {
+		field := "broadcaster"
+		if i > 0 {
+			field = fmt.Sprintf("channels[%d]", i-1)
+		}
+		if channel.Locale != "" {
+			check(field+".locale", channel.Locale)
+		}
+		for _, command := range channel.Commands {
+			for loc := range command.Responses {
+				check(fmt.Sprintf("%s.commands %s responses", field, command.Name), loc)
+			}
+		}
+	}
+	for _, command := range c.Commands {
+		for loc := range command.Responses {
+			check(fmt.Sprintf("commands %s responses", command.Name), loc)
+		}
+	}
+	return problems
+}
+
+// validate checks the moderation rules and their actions
+func (m Moderation) validate() []string {
+	var problems []string
+	if !ValidPermission(m.Exempt) {
+		problems = append(problems, fmt.Sprintf("moderation.exempt must be one of %s", strings.Join(PermissionLevels, ", ")))
+	}
+	if !ValidPermission(m.Links.Permission) {
+		problems = append(problems, fmt.Sprintf("moderation.links.permission must be one of %s", strings.Join(PermissionLevels, ", ")))
+	}
+	for _, pattern := range m.Blocklist.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			problems = append(problems, fmt.Sprintf("moderation.blocklist.patterns entry %q is not a valid regular expression: %v", pattern, err))
+		}
+	}
+	if m.Links.PermitDuration <= 0 {
+		problems = append(problems, "moderation.links.permit_duration must be a positive duration")
+	}
+	if m.Caps.MinLength < 0 || m.Caps.MaxRatio < 0 || m.Caps.MaxRatio > 1 {
+		problems = append(problems, "moderation.caps.min_length must not be negative and max_ratio must be between 0 and 1")
+	}
+	if m.Emotes.Max < 0 {
+		problems = append(problems, "moderation.emotes.max must not be negative")
+	}
+	if m.Repeats.Count < 0 || (m.Repeats.Count > 0 && m.Repeats.Window <= 0) {
+		problems = append(problems, "moderation.repeats.count must not be negative and needs a positive window")
+	}
+	if m.Enabled && len(m.Escalation) == 0 {
+		problems = append(problems, "moderation.escalation needs at least one step")
+	}
+	if m.StrikeWindow <= 0 {
+		problems = append(problems, "moderation.strike_window must be a positive duration")
+	}
+
+	// Rule actions are optional, the escalation applies when they are empty
+	type namedStep struct {
+		field string
+		step  ModerationStep
+	}
+	var steps []namedStep
+	for i, step := range m.Escalation {
+		steps = append(steps, namedStep{fmt.Sprintf("moderation.escalation[%d]", i), step})
+	}
+	rules := []namedStep{
+		{"moderation.blocklist.action", m.Blocklist.Action},
+		{"moderation.links.action", m.Links.Action},
+		{"moderation.caps.action", m.Caps.Action},
+		{"moderation.emotes.action", m.Emotes.Action},
+		{"moderation.repeats.action", m.Repeats.Action},
+	}
+	for _, rule := range rules {
+		if rule.step.Action != "" {
+			steps = append(steps, rule)
+		}
+	}
+	for _, named := range steps {
+		field, step := named.field, named.step
+		switch step.Action {
+		case ModerationDelete, ModerationBan:
+		case ModerationTimeout:
+			if step.Duration < time.Second || step.Duration > MaxTimeout {
+				problems = append(problems, fmt.Sprintf("%s.duration must be between 1s and %s", field, MaxTimeout))
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("%s must be %q, %q or %q", field, ModerationDelete, ModerationTimeout, ModerationBan))
+		}
+	}
+	return problems
+}
+
+// SupportedLocale reports whether loc is built in or defined under locale.messages
+func (c *Config) SupportedLocale(loc string) bool {
+	loc = locale.Normalize(loc)
+	if locale.Builtin(loc) {
+		return true
+	}
+	for defined := range c.Locale.Messages {
+		if locale.Normalize(defined) == loc {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the channel settings used by `!today`
+func (ch Channel) validate(field string) []string {
+	var problems []string
+	if ch.CategoryID != "" && !isNumeric(ch.CategoryID) {
+		problems = append(problems, field+".category_id must be a numeric Twitch category ID")
+	}
+	problems = append(problems, validateCommands(field+".commands", ch.Commands)...)
+	problems = append(problems, validateTimers(field+".timers", ch.Timers)...)
+	problems = append(problems, validatePresets(field+".presets", ch.Presets)...)
+	problems = append(problems, validateTags(field+".tags", ch.Tags)...)
+	return problems
+}
+
+// validateTags checks a tag list is accepted by Twitch
+func validateTags(field string, tags []string) []string {
+	var problems []string
+	if len(tags) > MaxTags {
+		problems = append(problems, fmt.Sprintf("%s allows at most %d tags", field, MaxTags))
+	}
+	for _, tag := range tags {
+		if !ValidTag(tag) {
+			problems = append(problems, fmt.Sprintf("%s entry %q must be 1-%d letters or digits", field, tag, MaxTagLength))
+		}
+	}
+	return problems
+}
+
+// validatePresets checks presets are named uniquely and only set valid channel info
+func validatePresets(field string, presets []Preset) []string {
+	var problems []string
+	seen := map[string]bool{}
+	for i, preset := range presets {
+		entry := fmt.Sprintf("%s[%d]", field, i)
+		if !ValidCommandName(preset.Name) || preset.Name == "default" {
+			problems = append(problems, fmt.Sprintf("%s.name %q must be a single word other than default", entry, preset.Name))
+		} else if seen[preset.Name] {
+			problems = append(problems, fmt.Sprintf("%s.name %q is used by another preset", entry, preset.Name))
+		}
+		seen[preset.Name] = true
+		if preset.CategoryID != "" && !isNumeric(preset.CategoryID) {
+			problems = append(problems, entry+".category_id must be a numeric Twitch category ID")
+		}
+		if preset.Title == "" && preset.CategoryID == "" && preset.Category == "" && len(preset.Tags) == 0 && preset.Language == "" {
+			problems = append(problems, entry+" must set at least one of title, category_id, category, tags or language")
+		}
+		problems = append(problems, validateTags(entry+".tags", preset.Tags)...)
+	}
+	return problems
+}
+
+// validateCommands checks names are usable in chat and unique within one list
+func validateCommands(field string, commands []Command) []string {
+	var problems []string
+	seen := map[string]bool{}
+	for i, command := range commands {
+		entry := fmt.Sprintf("%s[%d]", field, i)
+		if command.Response == "" {
+			problems = append(problems, entry+".response must not be empty")
+		}
+		if command.Permission != "" && !ValidPermission(command.Permission) {
+			problems = append(problems, fmt.Sprintf("%s.permission must be one of %s", entry, strings.Join(PermissionLevels, ", ")))
+		}
+		for _, name := range append([]string{command.Name}, command.Aliases...) {
+			if !ValidCommandName(name) {
+				problems = append(problems, fmt.Sprintf("%s name %q must be a single word without the ! prefix", entry, name))
+				continue
+			}
+			if seen[name] {
+				problems = append(problems, fmt.Sprintf("%s name %q is used by another command", entry, name))
+			}
+			seen[name] = true
+		}
+	}
+	return problems
+}
+
+// validateTimers checks timers are named uniquely and cannot flood chat
+func validateTimers(field string, timers []Timer) []string {
+	var problems []string
+	seen := map[string]bool{}
+	for i, timer := range timers {
+		entry := fmt.Sprintf("%s[%d]", field, i)
+		if !ValidCommandName(timer.Name) {
+			problems = append(problems, fmt.Sprintf("%s.name %q must be a single word", entry, timer.Name))
+		} else if seen[timer.Name] {
+			problems = append(problems, fmt.Sprintf("%s.name %q is used by another timer", entry, timer.Name))
+		}
+		seen[timer.Name] = true
+		if timer.Message == "" {
+			problems = append(problems, entry+".message must not be empty")
+		}
+		if timer.Interval < MinTimerInterval {
+			problems = append(problems, fmt.Sprintf("%s.interval must be at least %s", entry, MinTimerInterval))
+		}
+		if timer.MinMessages < 0 {
+			problems = append(problems, entry+".min_messages must not be negative")
+		}
+	}
+	return problems
+}
+
+// ValidTag reports whether a stream tag is accepted by Twitch: 1-25 letters or digits
+func ValidTag(tag string) bool {
+	if tag == "" || utf8.RuneCountInString(tag) > MaxTagLength {
+		return false
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidPermission reports whether level is one of PermissionLevels
+func ValidPermission(level string) bool {
+	return slices.Contains(PermissionLevels, level)
+}
+
+// ValidCommandName reports whether a command name can be typed in chat: a single word without the ! prefix
+func ValidCommandName(name string) bool {
+	return name != "" && !strings.HasPrefix(name, "!") && !strings.ContainsAny(name, " \t\n")
+}
+
+func isNumeric(value string) bool {
+	_, err := strconv.ParseUint(value, 10, 64)
+	return err == nil
+}
+
+func isURL(value string, schemes ...string) bool {
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	for _, scheme := range schemes {
+		if parsed.Scheme == scheme {
+			return true
+		}
+	}
+	return false
+}
+
+// ChannelCommands merges the global commands with the ones defined for a channel, the channel wins on name clashes
+func (c *Config) ChannelCommands(channel Channel) []Command {
+	commands := make([]Command, 0, len(c.Commands)+len(channel.Commands))
+	overridden := map[string]bool{}
+	for _, command := range channel.Commands {
+		overridden[command.Name] = true
+	}
+	for _, command := range c.Commands {
+		if !overridden[command.Name] {
+			commands = append(commands, command)
+		}
+	}
+	return append(commands, channel.Commands...)
+}
+
+// ChannelTimers merges the global timers with the ones defined for a channel, the channel wins on name clashes
+func (c *Config) ChannelTimers(channel Channel) []Timer {
+	timers := make([]Timer, 0, len(c.Timers)+len(channel.Timers))
+	overridden := map[string]bool{}
+	for _, timer := range channel.Timers {
+		overridden[timer.Name] = true
+	}
+	for _, timer := range c.Timers {
+		if !overridden[timer.Name] {
+			timers = append(timers, timer)
+		}
+	}
+	return append(timers, channel.Timers...)
+}
+
+// CommandLocale returns the locale a command is pinned to, empty when it follows the active locale
+func (c *Config) CommandLocale(name string) string {
+	for command, loc := range c.Locale.Commands {
+		if strings.EqualFold(command, name) {
+			return loc
+		}
+	}
+	return ""
+}
+
+// ChannelPresets merges the global presets with the ones defined for a channel, the channel wins on name clashes
+func (c *Config) ChannelPresets(channel Channel) []Preset {
+	presets := make([]Preset, 0, len(c.Presets)+len(channel.Presets))
+	overridden := map[string]bool{}
+	for _, preset := range channel.Presets {
+		overridden[preset.Name] = true
+	}
+	for _, preset := range c.Presets {
+		if !overridden[preset.Name] {
+			presets = append(presets, preset)
+		}
+	}
+	return append(presets, channel.Presets...)
+}
+
+// StreamLiveMessage is the go-live announcement for a channel, falling back to notifications.stream_live_message
+func (c *Config) StreamLiveMessage(channel Channel) string {
+	if channel.StreamLiveMessage != "" {
+		return channel.StreamLiveMessage
+	}
+	return c.Notifications.StreamLiveMessage
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig is the default configuration with the values Validate requires
+func validConfig() *Config {
+	cfg := Default()
+	cfg.Broadcaster.ID = "1792311"
+	cfg.EventSub.CallbackURL = "https://bot.example"
+	cfg.EventSub.Secret = "0123456789abcdef"
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr string
+	}{
+		{name: "defaults with required values", modify: func(*Config) {}},
+		{name: "gotify disabled by default", modify: func(cfg *Config) {
+			if cfg.Notifications.GotifyURL != "" {
+				t.Errorf("default gotify_url = %q, want empty", cfg.Notifications.GotifyURL)
+			}
+		}},
+		{name: "non numeric broadcaster", modify: func(cfg *Config) { cfg.Broadcaster.ID = "mvaldes" }, wantErr: "broadcaster.id"},
+		{name: "webhook needs https callback", modify: func(cfg *Config) { cfg.EventSub.CallbackURL = "http://bot.example" }, wantErr: "eventsub.callback_url"},
+		{name: "webhook secret too short", modify: func(cfg *Config) { cfg.EventSub.Secret = "short" }, wantErr: "eventsub.secret"},
+		{name: "websocket needs no secret", modify: func(cfg *Config) {
+			cfg.EventSub.Transport = TransportWebSocket
+			cfg.EventSub.Secret = ""
+			cfg.EventSub.CallbackURL = ""
+		}},
+		{name: "websocket needs ws url", modify: func(cfg *Config) {
+			cfg.EventSub.Transport = TransportWebSocket
+			cfg.EventSub.WebSocketURL = "https://eventsub.example"
+		}, wantErr: "eventsub.websocket_url"},
+		{name: "websocket serves one channel", modify: func(cfg *Config) {
+			cfg.EventSub.Transport = TransportWebSocket
+			cfg.Channels = []Channel{{ID: "42", RefreshTokenEnv: "OTHER_REFRESH_TOKEN"}}
+		}, wantErr: "only supports the primary channel"},
+		{name: "unknown transport", modify: func(cfg *Config) { cfg.EventSub.Transport = "grpc" }, wantErr: "eventsub.transport"},
+		{name: "reconcile interval", modify: func(cfg *Config) { cfg.EventSub.ReconcileInterval = 0 }, wantErr: "eventsub.reconcile_interval"},
+		{name: "gotify url", modify: func(cfg *Config) { cfg.Notifications.GotifyURL = "gotify.example" }, wantErr: "notifications.gotify_url"},
+		{name: "category id", modify: func(cfg *Config) { cfg.Broadcaster.CategoryID = "coding" }, wantErr: "broadcaster.category_id"},
+		{name: "too many tags", modify: func(cfg *Config) {
+			cfg.Broadcaster.Tags = strings.Fields("devops Español SpanishAndEnglish coding neovim k8s terraform go homelab nix gaming")
+		}, wantErr: "broadcaster.tags allows at most 10 tags"},
+		{name: "invalid tag", modify: func(cfg *Config) { cfg.Broadcaster.Tags = []string{"no spaces"} }, wantErr: "broadcaster.tags entry"},
+		{name: "command prefix", modify: func(cfg *Config) { cfg.CommandPrefix = "a" }, wantErr: "command_prefix"},
+		{name: "duplicate channel", modify: func(cfg *Config) {
+			cfg.Channels = []Channel{{ID: "1792311", RefreshTokenEnv: "OTHER_REFRESH_TOKEN"}}
+		}, wantErr: "configured more than once"},
+		{name: "extra channel needs refresh token env", modify: func(cfg *Config) { cfg.Channels = []Channel{{ID: "42"}} }, wantErr: "channels[0].refresh_token_env"},
+		{name: "bot is a separate account", modify: func(cfg *Config) { cfg.Bot.ID = "1792311" }, wantErr: "bot.id must be a separate account"},
+		{name: "negative cooldown", modify: func(cfg *Config) {
+			cfg.Cooldowns.Commands = map[string]Cooldown{"song": {Global: -time.Second}}
+		}, wantErr: "cooldowns.commands.song"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() = %v, want an error mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadShippedConfig(t *testing.T) {
+	t.Setenv(pathEnv, "../../config.yaml")
+	t.Setenv("EVENTSUB_SECRET", "0123456789abcdef")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("config.yaml does not load: %v", err)
+	}
+	if cfg.Broadcaster.ID == "" {
+		t.Error("config.yaml has no broadcaster")
+	}
+}
@@ -0,0 +1,204 @@
+// Package eventsub connects to the Twitch EventSub websocket and forwards its events
+package eventsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/mvaldes14/twitch-bot/pkgs/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	welcomeTimeout   = 10 * time.Second
+	defaultKeepalive = 10 * time.Second
+	maxBackoff       = time.Minute
+	maxMessageBytes  = 1 << 20
+)
+
+// keepaliveGrace is how long past the keepalive timeout a silent connection is kept, a var so tests can shorten it
+var keepaliveGrace = 5 * time.Second
+
+var (
+	errNoWelcome   = errors.New("websocket did not start with a session_welcome message")
+	errEmptySessID = errors.New("session_welcome did not include a session id")
+)
+
+// Handler receives the events read from the websocket
+type Handler interface {
+	// HandleSession is called for every new session, subscriptions must be created for sessionID.
+	// It is not called when Twitch moves an existing session through session_reconnect.
+	HandleSession(ctx context.Context, sessionID string)
+	// HandleNotification receives the payload of a notification, which has the same
+	// subscription and event fields as a webhook notification body
+	HandleNotification(ctx context.Context, messageID, subscriptionType string, payload []byte)
+	// HandleRevocation receives the payload of a revocation message
+	HandleRevocation(ctx context.Context, messageID string, payload []byte)
+}
+
+// Client keeps a websocket session open and reconnects when it drops
+type Client struct {
+	Log     *telemetry.CustomLogger
+	URL     string
+	Handler Handler
+}
+
+// NewClient creates a new websocket client for url, which is configurable so a local server can be used instead of Twitch
+func NewClient(url string, handler Handler) *Client {
+	logger := telemetry.NewLogger("eventsub")
+	return &Client{
+		Log:     logger,
+		URL:     url,
+		Handler: handler,
+	}
+}
+
+// Run connects to the websocket and keeps reconnecting with backoff until ctx is cancelled
+func (c *Client) Run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		c.Log.Info("Connecting to EventSub websocket at " + c.URL)
+		connectedAt := time.Now()
+		err := c.session(ctx)
+		if ctx.Err() != nil {
+			c.Log.Info("EventSub websocket client stopped")
+			return
+		}
+		c.Log.Error("EventSub websocket session ended, reconnecting in "+backoff.String(), err)
+		telemetry.IncrementWebSocketReconnect(ctx, "error")
+
+		// A session that stayed up for a while resets the backoff
+		if time.Since(connectedAt) > maxBackoff {
+			backoff = time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// session runs a single websocket session, following session_reconnect messages,
+// until the connection fails or ctx is cancelled
+func (c *Client) session(ctx context.Context) error {
+	conn, welcome, err := c.dial(ctx, c.URL)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.CloseNow() }()
+
+	c.Log.Info("EventSub websocket session started: " + welcome.Session.ID)
+	c.Handler.HandleSession(ctx, welcome.Session.ID)
+	keepalive := keepaliveFor(welcome)
+
+	for {
+		msg, err := c.read(ctx, conn, keepalive+keepaliveGrace)
+		if err != nil {
+			return err
+		}
+
+		switch msg.Metadata.MessageType {
+		case MessageTypeKeepalive:
+			continue
+		case MessageTypeNotification:
+			c.Handler.HandleNotification(ctx, msg.Metadata.MessageID, msg.Metadata.SubscriptionType, msg.Payload)
+		case MessageTypeRevocation:
+			c.Handler.HandleRevocation(ctx, msg.Metadata.MessageID, msg.Payload)
+		case MessageTypeReconnect:
+			var reconnect SessionPayload
+			if err := json.Unmarshal(msg.Payload, &reconnect); err != nil {
+				return fmt.Errorf("failed to unmarshal session_reconnect: %w", err)
+			}
+			// Twitch keeps subscriptions when the new connection is opened before the old one closes
+			newConn, newWelcome, err := c.dial(ctx, reconnect.Session.ReconnectURL)
+			if err != nil {
+				return fmt.Errorf("failed to follow session_reconnect: %w", err)
+			}
+			_ = conn.Close(websocket.StatusNormalClosure, "reconnecting")
+			conn = newConn
+			keepalive = keepaliveFor(newWelcome)
+			c.Log.Info("EventSub websocket session moved to " + reconnect.Session.ReconnectURL)
+			telemetry.IncrementWebSocketReconnect(ctx, "session_reconnect")
+		default:
+			c.Log.Info("Ignoring unknown EventSub websocket message type: " + msg.Metadata.MessageType)
+		}
+	}
+}
+
+// dial opens a websocket connection and waits for its session_welcome message
+func (c *Client) dial(ctx context.Context, url string) (*websocket.Conn, SessionPayload, error) {
+	_, span := telemetry.StartExternalSpan(ctx, "twitch.eventsub_websocket_connect", "twitch", "eventsub_websocket_connect")
+	defer span.End()
+
+	var welcome SessionPayload
+	dialCtx, cancel := context.WithTimeout(ctx, welcomeTimeout)
+	defer cancel()
+
+	conn, resp, err := websocket.Dial(dialCtx, url, nil)
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+	if err != nil {
+		telemetry.RecordError(span, err)
+		return nil, welcome, fmt.Errorf("failed to connect to %s: %w", url, err)
+	}
+	conn.SetReadLimit(maxMessageBytes)
+
+	msg, err := c.read(ctx, conn, welcomeTimeout)
+	if err != nil {
+		_ = conn.CloseNow()
+		telemetry.RecordError(span, err)
+		return nil, welcome, err
+	}
+	if msg.Metadata.MessageType != MessageTypeWelcome {
+		_ = conn.CloseNow()
+		telemetry.RecordError(span, errNoWelcome)
+		return nil, welcome, errNoWelcome
+	}
+	if err := json.Unmarshal(msg.Payload, &welcome); err != nil {
+		_ = conn.CloseNow()
+		telemetry.RecordError(span, err)
+		return nil, welcome, fmt.Errorf("failed to unmarshal session_welcome: %w", err)
+	}
+	if welcome.Session.ID == "" {
+		_ = conn.CloseNow()
+		telemetry.RecordError(span, errEmptySessID)
+		return nil, welcome, errEmptySessID
+	}
+
+	telemetry.AddSpanAttributes(span,
+		attribute.String("eventsub.session_id", welcome.Session.ID),
+		attribute.Int("eventsub.keepalive_seconds", welcome.Session.KeepaliveTimeoutSeconds),
+	)
+	return conn, welcome, nil
+}
+
+// read waits up to timeout for the next message; Twitch sends a keepalive whenever
+// there are no events, so silence past the keepalive timeout means the connection is dead
+func (c *Client) read(ctx context.Context, conn *websocket.Conn, timeout time.Duration) (Message, error) {
+	var msg Message
+	readCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	_, data, err := conn.Read(readCtx)
+	if err != nil {
+		return msg, fmt.Errorf("failed to read from websocket: %w", err)
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return msg, fmt.Errorf("failed to unmarshal websocket message: %w", err)
+	}
+	return msg, nil
+}
+
+func keepaliveFor(welcome SessionPayload) time.Duration {
+	if welcome.Session.KeepaliveTimeoutSeconds <= 0 {
+		return defaultKeepalive
+	}
+	return time.Duration(welcome.Session.KeepaliveTimeoutSeconds) * time.Second
+}
@@ -0,0 +1,213 @@
+package eventsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// recordingHandler collects what the client forwards
+type recordingHandler struct {
+	mu            sync.Mutex
+	sessions      []string
+	notifications chan notification
+}
+
+type notification struct {
+	messageID        string
+	subscriptionType string
+	payload          string
+}
+
+func newRecordingHandler() *recordingHandler {
+	return &recordingHandler{notifications: make(chan notification, 10)}
+}
+
+func (h *recordingHandler) HandleSession(_ context.Context, sessionID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sessions = append(h.sessions, sessionID)
+}
+
+func (h *recordingHandler) HandleNotification(_ context.Context, messageID, subscriptionType string, payload []byte) {
+	h.notifications <- notification{messageID: messageID, subscriptionType: subscriptionType, payload: string(payload)}
+}
+
+func (h *recordingHandler) HandleRevocation(context.Context, string, []byte) {}
+
+func (h *recordingHandler) sessionIDs() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.sessions...)
+}
+
+// frame builds a websocket message as Twitch sends it
+func frame(t *testing.T, messageType, messageID, subscriptionType string, payload any) []byte {
+	t.Helper()
+	var msg Message
+	msg.Metadata.MessageID = messageID
+	msg.Metadata.MessageType = messageType
+	msg.Metadata.MessageTimestamp = time.Now()
+	msg.Metadata.SubscriptionType = subscriptionType
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.Payload = raw
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// welcomeFrame builds a session_welcome, or a session_reconnect when reconnectURL is set
+func welcomeFrame(t *testing.T, messageType, sessionID string, keepalive int, reconnectURL string) []byte {
+	t.Helper()
+	var session SessionPayload
+	session.Session.ID = sessionID
+	session.Session.Status = "connected"
+	session.Session.KeepaliveTimeoutSeconds = keepalive
+	session.Session.ReconnectURL = reconnectURL
+	return frame(t, messageType, "welcome-"+sessionID, "", session)
+}
+
+// newServer starts a websocket server that runs script for every connection
+func newServer(t *testing.T, script func(ctx context.Context, conn *websocket.Conn, r *http.Request)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			t.Errorf("accept: %v", err)
+			return
+		}
+		defer func() { _ = conn.CloseNow() }()
+		script(r.Context(), conn, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func wsURL(srv *httptest.Server, path string) string {
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + path
+}
+
+func write(ctx context.Context, t *testing.T, conn *websocket.Conn, data []byte) {
+	t.Helper()
+	if err := conn.Write(ctx, websocket.MessageText, data); err != nil {
+		t.Errorf("write: %v", err)
+	}
+}
+
+func TestSessionWelcomeAndNotification(t *testing.T) {
+	srv := newServer(t, func(ctx context.Context, conn *websocket.Conn, _ *http.Request) {
+		write(ctx, t, conn, welcomeFrame(t, MessageTypeWelcome, "session-1", 10, ""))
+		write(ctx, t, conn, frame(t, MessageTypeKeepalive, "keepalive-1", "", struct{}{}))
+		write(ctx, t, conn, frame(t, MessageTypeNotification, "message-1", "channel.follow", map[string]string{"event": "follow"}))
+		_, _, _ = conn.Read(ctx)
+	})
+
+	handler := newRecordingHandler()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go NewClient(wsURL(srv, "/ws"), handler).Run(ctx)
+
+	select {
+	case got := <-handler.notifications:
+		if got.messageID != "message-1" || got.subscriptionType != "channel.follow" || got.payload != `{"event":"follow"}` {
+			t.Errorf("notification = %+v", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification was not dispatched")
+	}
+	if ids := handler.sessionIDs(); len(ids) != 1 || ids[0] != "session-1" {
+		t.Errorf("sessions = %q, want [session-1]", ids)
+	}
+}
+
+func TestSessionRequiresWelcome(t *testing.T) {
+	srv := newServer(t, func(ctx context.Context, conn *websocket.Conn, _ *http.Request) {
+		write(ctx, t, conn, frame(t, MessageTypeKeepalive, "keepalive-1", "", struct{}{}))
+		_, _, _ = conn.Read(ctx)
+	})
+
+	handler := newRecordingHandler()
+	err := NewClient(wsURL(srv, "/ws"), handler).session(context.Background())
+	if !errors.Is(err, errNoWelcome) {
+		t.Fatalf("session error = %v, want %v", err, errNoWelcome)
+	}
+	if ids := handler.sessionIDs(); len(ids) != 0 {
+		t.Errorf("sessions = %q, want none", ids)
+	}
+}
+
+func TestSessionKeepaliveTimeout(t *testing.T) {
+	grace := keepaliveGrace
+	keepaliveGrace = 100 * time.Millisecond
+	t.Cleanup(func() { keepaliveGrace = grace })
+
+	srv := newServer(t, func(ctx context.Context, conn *websocket.Conn, _ *http.Request) {
+		write(ctx, t, conn, welcomeFrame(t, MessageTypeWelcome, "session-1", 1, ""))
+		// Stay silent past the keepalive timeout
+		_, _, _ = conn.Read(ctx)
+	})
+
+	start := time.Now()
+	err := NewClient(wsURL(srv, "/ws"), newRecordingHandler()).session(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("session error = %v, want a read deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > 5*time.Second {
+		t.Errorf("session ended after %s, want the keepalive timeout plus grace", elapsed)
+	}
+}
+
+func TestSessionReconnect(t *testing.T) {
+	oldClosed := make(chan websocket.StatusCode, 1)
+	var srv *httptest.Server
+	srv = newServer(t, func(ctx context.Context, conn *websocket.Conn, r *http.Request) {
+		if r.URL.Path == "/reconnect" {
+			write(ctx, t, conn, welcomeFrame(t, MessageTypeWelcome, "session-1", 10, ""))
+			// Twitch only moves events once the old connection is closed
+			select {
+			case <-oldClosed:
+			case <-time.After(5 * time.Second):
+				t.Error("old connection was not closed")
+				return
+			}
+			write(ctx, t, conn, frame(t, MessageTypeNotification, "message-2", "stream.online", map[string]string{"event": "online"}))
+			_, _, _ = conn.Read(ctx)
+			return
+		}
+		write(ctx, t, conn, welcomeFrame(t, MessageTypeWelcome, "session-1", 10, ""))
+		write(ctx, t, conn, welcomeFrame(t, MessageTypeReconnect, "session-1", 0, wsURL(srv, "/reconnect")))
+		_, _, err := conn.Read(ctx)
+		oldClosed <- websocket.CloseStatus(err)
+	})
+
+	handler := newRecordingHandler()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go NewClient(wsURL(srv, "/ws"), handler).Run(ctx)
+
+	select {
+	case got := <-handler.notifications:
+		if got.messageID != "message-2" || got.subscriptionType != "stream.online" {
+			t.Errorf("notification = %+v", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification on the new connection was not dispatched")
+	}
+	// The session moved, so subscriptions must not be created again
+	if ids := handler.sessionIDs(); len(ids) != 1 {
+		t.Errorf("sessions = %q, want one", ids)
+	}
+}
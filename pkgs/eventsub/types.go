@@ -0,0 +1,39 @@
+// Package eventsub provides types and structures for the EventSub websocket transport.
+package eventsub
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Websocket message types sent in the metadata of every frame
+const (
+	MessageTypeWelcome      = "session_welcome"
+	MessageTypeKeepalive    = "session_keepalive"
+	MessageTypeReconnect    = "session_reconnect"
+	MessageTypeNotification = "notification"
+	MessageTypeRevocation   = "revocation"
+)
+
+// Message is a single frame received from the EventSub websocket
+type Message struct {
+	Metadata struct {
+		MessageID           string    `json:"message_id"`
+		MessageType         string    `json:"message_type"`
+		MessageTimestamp    time.Time `json:"message_timestamp"`
+		SubscriptionType    string    `json:"subscription_type,omitempty"`
+		SubscriptionVersion string    `json:"subscription_version,omitempty"`
+	} `json:"metadata"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// SessionPayload is the payload of welcome and reconnect messages
+type SessionPayload struct {
+	Session struct {
+		ID                      string    `json:"id"`
+		Status                  string    `json:"status"`
+		KeepaliveTimeoutSeconds int       `json:"keepalive_timeout_seconds"`
+		ReconnectURL            string    `json:"reconnect_url"`
+		ConnectedAt             time.Time `json:"connected_at"`
+	} `json:"session"`
+}
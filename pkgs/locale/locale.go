@@ -0,0 +1,261 @@
+// Package locale holds the message catalog the bot answers with, one template per message key and locale
+package locale
+
+import (
+	"sort"
+	"strings"
+)
+
+// Default is the locale used when the configuration does not pick one
+const Default = "en"
+
+// Message keys of the catalog. Templates use {name} variables, listed next to each key
+const (
+	Follow             = "follow"       // {user}
+	Sub                = "sub"          // {user}
+	Cheer              = "cheer"        // {user}, {bits}
+	StreamLive         = "stream_live"  // {channel}, empty templates skip the go-live notification
+	SongPlaying        = "song_playing" // {artist}, {song}
+	SongNone           = "song_none"
+	SongError          = "song_error"
+	Usage              = "usage"      // {user}, {reason}
+	UsageHint          = "usage_hint" // {user}, {reason}, {usage}
+	MissingArguments   = "missing_arguments"
+	Cooldown           = "cooldown"            // {user}, {command}, {wait}
+	LangCurrent        = "lang_current"        // {locale}, {locales}
+	LangChanged        = "lang_changed"        // {locale}
+	LangUnknown        = "lang_unknown"        // {locale}
+	Permit             = "permit"              // {user}, {duration}
+	Shoutout           = "shoutout"            // {user}, {login}, {game}
+	ShoutoutNoGame     = "shoutout_no_game"    // {user}, {login}
+	TimedOut           = "timed_out"           // {user}, {duration}
+	Banned             = "banned"              // {user}
+	Unbanned           = "unbanned"            // {user}
+	Raiding            = "raiding"             // {user}, {login}
+	MarkerCreated      = "marker_created"      // {position}
+	UserNotFound       = "user_not_found"      // {user}
+	CommandFailed      = "command_failed"      // {user}, {reason}
+	CommandError       = "command_error"       // {user}
+	TitleChanged       = "title_changed"       // {title}
+	TitleTooLong       = "title_too_long"      // {max}
+	GameChanged        = "game_changed"        // {game}
+	CategoryNotFound   = "category_not_found"  // {category}
+	TagsCurrent        = "tags_current"        // {tags}
+	TagsChanged        = "tags_changed"        // {tags}
+	InvalidTag         = "invalid_tag"         // {tag}, {max}
+	TooManyTags        = "too_many_tags"       // {max}
+	PresetCurrent      = "preset_current"      // {preset}, {presets}
+	PresetApplied      = "preset_applied"      // {preset}
+	PresetNotFound     = "preset_not_found"    // {preset}, {presets}
+	ClipCreated        = "clip_created"        // {user}, {url}
+	ClipFailed         = "clip_failed"         // {user}
+	ClipShared         = "clip_shared"         // {user}, {channel}, {url}, sent to Discord
+	StreamClips        = "stream_clips"        // {channel}, {count}, {clips}, sent to Discord when the stream ends
+	PollStarted        = "poll_started"        // {title}, {choices}, {duration}
+	PollEnded          = "poll_ended"          // {title}, {winner}, {votes}, {results}
+	PredictionStarted  = "prediction_started"  // {title}, {outcomes}, {duration}
+	PredictionLocked   = "prediction_locked"   // {title}
+	PredictionResolved = "prediction_resolved" // {title}, {outcome}, {users}, {points}
+	PredictionCanceled = "prediction_canceled" // {title}
+	NoPrediction       = "no_prediction"
+	NothingToCancel    = "nothing_to_cancel"
+	OutcomeNotFound    = "outcome_not_found" // {outcome}, {outcomes}
+	// Notices sent when moderation.notice is on, named after the rule that removed the message
+	ModerationBlocklist = "moderation_blocklist" // {user}
+	ModerationLinks     = "moderation_links"     // {user}, {permit}
+	ModerationCaps      = "moderation_caps"      // {user}
+	ModerationEmotes    = "moderation_emotes"    // {user}
+	ModerationRepeats   = "moderation_repeats"   // {user}
+)
+
+// builtin are the templates shipped with the bot, every key is defined for Default
+var builtin = map[string]map[string]string{
+	"en": {
+		Follow:             "Thanks for the follow: {user}",
+		Sub:                "Thanks for the sub: {user}",
+		Cheer:              "Thanks for the bits: {user}",
+		StreamLive:         "",
+		SongPlaying:        "Now playing: {artist} - {song}",
+		SongNone:           "No song currently playing",
+		SongError:          "Sorry, couldn't get the current song",
+		Usage:              "@{user} {reason}",
+		UsageHint:          "@{user} {reason}, usage: {usage}",
+		MissingArguments:   "missing arguments",
+		Cooldown:           "@{user} {command} is on cooldown, try again in {wait}",
+		LangCurrent:        "Language: {locale}, available: {locales}",
+		LangChanged:        "Language set to {locale}",
+		LangUnknown:        "unknown language {locale}",
+		Permit:             "{user} may post links for {duration}",
+		Shoutout:           "Go follow {user} at https://twitch.tv/{login}, they were last playing {game}",
+		ShoutoutNoGame:     "Go follow {user} at https://twitch.tv/{login}",
+		TimedOut:           "{user} was timed out for {duration}",
+		Banned:             "{user} was banned",
+		Unbanned:           "{user} was unbanned",
+		Raiding:            "Raiding {user}! https://twitch.tv/{login}",
+		MarkerCreated:      "Stream marker added at {position}",
+		UserNotFound:       "user {user} not found",
+		CommandFailed:      "@{user} Twitch refused: {reason}",
+		CommandError:       "@{user} that didn't work, try again later",
+		TitleChanged:       "Title set to: {title}",
+		TitleTooLong:       "the title must be at most {max} characters",
+		GameChanged:        "Category set to {game}",
+		CategoryNotFound:   "no category matches {category}",
+		TagsCurrent:        "Tags: {tags}",
+		TagsChanged:        "Tags set to: {tags}",
+		InvalidTag:         "tag {tag} must be 1-{max} letters or digits",
+		TooManyTags:        "a channel can have at most {max} tags",
+		PresetCurrent:      "Preset: {preset}, available: {presets}",
+		PresetApplied:      "Switched to the {preset} preset",
+		PresetNotFound:     "unknown preset {preset}, available: {presets}",
+		ClipCreated:        "@{user} clip ready: {url}",
+		ClipFailed:         "@{user} Twitch didn't finish the clip, try again",
+		ClipShared:         "{user} clipped {channel}: {url}",
+		StreamClips:        "{count} clips from today's stream on {channel}: {clips}",
+		PollStarted:        "Poll: {title} Vote for {choices}, {duration} left",
+		PollEnded:          "Poll closed: {title} Winner: {winner} with {votes} votes ({results})",
+		PredictionStarted:  "Prediction: {title} Pick {outcomes}, {duration} to predict",
+		PredictionLocked:   "Predictions are locked: {title}",
+		PredictionResolved: "Prediction over: {title} {outcome} won, {users} chatters share {points} points",
+		PredictionCanceled: "Prediction canceled, points refunded: {title}",
+		NoPrediction:       "there is no open prediction",
+		NothingToCancel:    "there is no open prediction or poll",
+		OutcomeNotFound:    "unknown outcome {outcome}, pick one of {outcomes}",
+
+		ModerationBlocklist: "@{user} that is not allowed here",
+		ModerationLinks:     "@{user} links are not allowed, ask a moderator for a {permit}",
+		ModerationCaps:      "@{user} please don't shout",
+		ModerationEmotes:    "@{user} too many emotes",
+		ModerationRepeats:   "@{user} please don't repeat the same message",
+	},
+	"es": {
+		Follow:             "Gracias por el follow: {user}",
+		Sub:                "Gracias por el sub: {user}",
+		Cheer:              "Gracias por los bits: {user}",
+		StreamLive:         "",
+		SongPlaying:        "Sonando: {artist} - {song}",
+		SongNone:           "No hay ninguna canción sonando",
+		SongError:          "Lo siento, no pude obtener la canción actual",
+		Usage:              "@{user} {reason}",
+		UsageHint:          "@{user} {reason}, uso: {usage}",
+		MissingArguments:   "faltan argumentos",
+		Cooldown:           "@{user} {command} está en cooldown, intenta de nuevo en {wait}",
+		LangCurrent:        "Idioma: {locale}, disponibles: {locales}",
+		LangChanged:        "Idioma cambiado a {locale}",
+		LangUnknown:        "idioma desconocido {locale}",
+		Permit:             "{user} puede enviar links durante {duration}",
+		Shoutout:           "Sigan a {user} en https://twitch.tv/{login}, estuvo jugando {game}",
+		ShoutoutNoGame:     "Sigan a {user} en https://twitch.tv/{login}",
+		TimedOut:           "{user} fue silenciado por {duration}",
+		Banned:             "{user} fue baneado",
+		Unbanned:           "{user} fue desbaneado",
+		Raiding:            "¡Raid a {user}! https://twitch.tv/{login}",
+		MarkerCreated:      "Marcador agregado en {position}",
+		UserNotFound:       "no encontré al usuario {user}",
+		CommandFailed:      "@{user} Twitch lo rechazó: {reason}",
+		CommandError:       "@{user} no funcionó, intenta más tarde",
+		TitleChanged:       "Título cambiado a: {title}",
+		TitleTooLong:       "el título debe tener como máximo {max} caracteres",
+		GameChanged:        "Categoría cambiada a {game}",
+		CategoryNotFound:   "ninguna categoría coincide con {category}",
+		TagsCurrent:        "Etiquetas: {tags}",
+		TagsChanged:        "Etiquetas cambiadas a: {tags}",
+		InvalidTag:         "la etiqueta {tag} debe tener de 1 a {max} letras o dígitos",
+		TooManyTags:        "un canal puede tener como máximo {max} etiquetas",
+		PresetCurrent:      "Preset: {preset}, disponibles: {presets}",
+		PresetApplied:      "Cambiado al preset {preset}",
+		PresetNotFound:     "preset desconocido {preset}, disponibles: {presets}",
+		ClipCreated:        "@{user} clip listo: {url}",
+		ClipFailed:         "@{user} Twitch no terminó el clip, intenta de nuevo",
+		ClipShared:         "{user} hizo un clip de {channel}: {url}",
+		StreamClips:        "{count} clips del stream de hoy en {channel}: {clips}",
+		PollStarted:        "Encuesta: {title} Voten por {choices}, quedan {duration}",
+		PollEnded:          "Encuesta cerrada: {title} Ganó: {winner} con {votes} votos ({results})",
+		PredictionStarted:  "Predicción: {title} Elijan {outcomes}, tienen {duration} para predecir",
+		PredictionLocked:   "Predicciones cerradas: {title}",
+		PredictionResolved: "Predicción terminada: {title} ganó {outcome}, {users} chatters se reparten {points} puntos",
+		PredictionCanceled: "Predicción cancelada, puntos devueltos: {title}",
+		NoPrediction:       "no hay ninguna predicción abierta",
+		NothingToCancel:    "no hay ninguna predicción ni encuesta abierta",
+		OutcomeNotFound:    "resultado desconocido {outcome}, elige uno de {outcomes}",
+
+		ModerationBlocklist: "@{user} eso no está permitido aquí",
+		ModerationLinks:     "@{user} no se permiten links, pide un {permit} a un moderador",
+		ModerationCaps:      "@{user} por favor no grites",
+		ModerationEmotes:    "@{user} demasiados emotes",
+		ModerationRepeats:   "@{user} por favor no repitas el mismo mensaje",
+	},
+}
+
+// Catalog resolves message templates by locale, falling back to the default locale and then to Default
+type Catalog struct {
+	fallback string
+	messages map[string]map[string]string
+}
+
+// NewCatalog builds a catalog from the built-in templates with overrides applied on top.
+// Overrides are keyed by locale then message key and may add locales that are not built in
+func NewCatalog(fallback string, overrides map[string]map[string]string) *Catalog {
+	c := &Catalog{
+		fallback: Normalize(fallback),
+		messages: map[string]map[string]string{},
+	}
+	for _, source := range []map[string]map[string]string{builtin, overrides} {
+		for loc, templates := range source {
+			loc = Normalize(loc)
+			if c.messages[loc] == nil {
+				c.messages[loc] = map[string]string{}
+			}
+			for key, template := range templates {
+				c.messages[loc][key] = template
+			}
+		}
+	}
+	if c.fallback == "" {
+		c.fallback = Default
+	}
+	return c
+}
+
+// Supported reports whether the catalog has templates for a locale
+func (c *Catalog) Supported(loc string) bool {
+	_, ok := c.messages[Normalize(loc)]
+	return ok
+}
+
+// Locales returns the locales of the catalog sorted by name
+func (c *Catalog) Locales() []string {
+	locales := make([]string, 0, len(c.messages))
+	for loc := range c.messages {
+		locales = append(locales, loc)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Format renders the template of key in loc, vars are name and value pairs such as "{user}", name.
+// Missing templates fall back to the default locale, then to Default, then to the key itself
+func (c *Catalog) Format(loc, key string, vars ...string) string {
+	for _, candidate := range []string{Normalize(loc), c.fallback, Default} {
+		if template, ok := c.messages[candidate][key]; ok {
+			return strings.NewReplacer(vars...).Replace(template)
+		}
+	}
+	return key
+}
+
+// Normalize lower cases a locale name, e.g. "ES" becomes "es"
+func Normalize(loc string) string {
+	return strings.ToLower(strings.TrimSpace(loc))
+}
+
+// Builtin reports whether a locale ships with the bot
+func Builtin(loc string) bool {
+	_, ok := builtin[Normalize(loc)]
+	return ok
+}
+
+// KnownKey reports whether key is a message of the catalog
+func KnownKey(key string) bool {
+	_, ok := builtin[Default][key]
+	return ok
+}
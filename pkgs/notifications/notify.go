@@ -15,7 +15,6 @@ import (
 
 const (
 	discordWebhookURL = "DISCORD_WEBHOOK"
-	gotifyURL         = "https://gotify.mvaldes.dev/message"
 	gotifyAppToken    = "GOTIFY_APPLICATION_TOKEN"
 )
 
@@ -26,17 +25,19 @@ var (
 
 // NotificationService struct to hold the properties
 type NotificationService struct {
-	Log    telemetry.CustomLogger
-	Client http.Client
+	Log       telemetry.CustomLogger
+	Client    http.Client
+	GotifyURL string
 }
 
 // NewNotificationService returns a new instance of NotificationService
-func NewNotificationService() *NotificationService {
+func NewNotificationService(gotifyURL string) *NotificationService {
 	logger := *telemetry.NewLogger("discord")
 	client := &http.Client{}
 	return &NotificationService{
-		Log:    logger,
-		Client: *client,
+		Log:       logger,
+		Client:    *client,
+		GotifyURL: gotifyURL,
 	}
 }
 
@@ -68,6 +69,9 @@ func (n *NotificationService) SendNotification(msg string) error {
 		telemetry.IncrementNotificationSent(ctx, "discord", "success")
 	}
 
+	if n.GotifyURL == "" {
+		return nil
+	}
 	n.Log.Info("Sending message to gotify")
 	token := os.Getenv(gotifyAppToken)
 	if token == "" {
@@ -87,7 +91,7 @@ func (n *NotificationService) SendNotification(msg string) error {
 		return err
 	}
 
-	req, err = http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s?token=%s", gotifyURL, token), &body)
+	req, err = http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s?token=%s", n.GotifyURL, token), &body)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,172 @@
+// Package routes handles the routes of the server
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/actions"
+	"github.com/mvaldes14/twitch-bot/pkgs/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CustomCommandRequest is the body for creating or updating a custom command
+type CustomCommandRequest struct {
+	// Channel is the broadcaster ID owning the command, defaults to the primary channel
+	Channel  string `json:"channel"`
+	Name     string `json:"name"`
+	Response string `json:"response"`
+	// Permission is the lowest role allowed to run the command, defaults to everyone
+	Permission string `json:"permission"`
+	// Reply threads the response as a reply to the invoking message, defaults to true
+	Reply *bool `json:"reply"`
+}
+
+// customCommandList is the response of ListCommandsHandler
+type customCommandList struct {
+	Channel  string                  `json:"channel"`
+	Total    int                     `json:"total"`
+	Commands []actions.CustomCommand `json:"commands"`
+}
+
+// requestChannel resolves the channel of an admin request, defaulting to the primary channel
+func (rt *Router) requestChannel(channelID string) (string, error) {
+	if channelID == "" {
+		return rt.Config.Broadcaster.ID, nil
+	}
+	if _, ok := rt.Config.Channel(channelID); !ok {
+		return "", fmt.Errorf("%w: %s", errorUnknownChannel, channelID)
+	}
+	return channelID, nil
+}
+
+// errorStatus maps custom command and timer errors to an http status
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, errorUnknownChannel), errors.Is(err, actions.ErrInvalidCommand), errors.Is(err, actions.ErrInvalidTimer), errors.Is(err, actions.ErrInvalidPreset):
+		return http.StatusBadRequest
+	case errors.Is(err, actions.ErrCommandNotFound), errors.Is(err, actions.ErrTimerNotFound), errors.Is(err, actions.ErrPresetNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, actions.ErrCommandConflict), errors.Is(err, actions.ErrTimerConflict), errors.Is(err, actions.ErrPresetConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeJSON writes a JSON response, errors are wrapped as {"error": "..."}
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	if err, ok := body.(error); ok {
+		body = map[string]string{"error": err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if body != nil {
+		_ = json.NewEncoder(w).Encode(body)
+	}
+}
+
+// ListCommandsHandler lists the custom commands of the ?channel= broadcaster ID
+func (rt *Router) ListCommandsHandler(w http.ResponseWriter, r *http.Request) {
+	channelID, err := rt.requestChannel(r.URL.Query().Get("channel"))
+	if err != nil {
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	commands, err := rt.Actions.ListCustomCommands(channelID)
+	if err != nil {
+		rt.Log.Error("Could not list custom commands", err)
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, customCommandList{
+		Channel:  channelID,
+		Total:    len(commands),
+		Commands: commands,
+	})
+}
+
+// GetCommandHandler returns the custom command named by the {name} path value
+func (rt *Router) GetCommandHandler(w http.ResponseWriter, r *http.Request) {
+	channelID, err := rt.requestChannel(r.URL.Query().Get("channel"))
+	if err != nil {
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	cmd, err := rt.Actions.GetCustomCommand(channelID, r.PathValue("name"))
+	if err != nil {
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, cmd)
+}
+
+// CreateCommandHandler adds a custom command
+func (rt *Router) CreateCommandHandler(w http.ResponseWriter, r *http.Request) {
+	_, span := telemetry.StartSpan(r.Context(), "create_custom_command")
+	defer span.End()
+
+	var req CustomCommandRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		telemetry.RecordError(span, err)
+		writeJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	rt.saveCommand(w, req, http.StatusCreated, rt.Actions.CreateCustomCommand)
+}
+
+// UpdateCommandHandler replaces the response of the custom command named by the {name} path value
+func (rt *Router) UpdateCommandHandler(w http.ResponseWriter, r *http.Request) {
+	_, span := telemetry.StartSpan(r.Context(), "update_custom_command")
+	defer span.End()
+
+	var req CustomCommandRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		telemetry.RecordError(span, err)
+		writeJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	req.Name = r.PathValue("name")
+	rt.saveCommand(w, req, http.StatusOK, rt.Actions.UpdateCustomCommand)
+}
+
+// saveCommand stores a custom command with the given create or update action
+func (rt *Router) saveCommand(w http.ResponseWriter, req CustomCommandRequest, status int, save func(string, actions.CustomCommand) (actions.CustomCommand, error)) {
+	channelID, err := rt.requestChannel(req.Channel)
+	if err != nil {
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	cmd, err := save(channelID, actions.CustomCommand{Name: req.Name, Response: req.Response, Permission: req.Permission, Reply: req.Reply})
+	if err != nil {
+		rt.Log.Error("Could not save custom command "+req.Name, err)
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	rt.Log.Info(fmt.Sprintf("[COMMAND: SAVED] !%s for channel %s", cmd.Name, channelID))
+	writeJSON(w, status, cmd)
+}
+
+// DeleteCommandHandler removes the custom command named by the {name} path value
+func (rt *Router) DeleteCommandHandler(w http.ResponseWriter, r *http.Request) {
+	_, span := telemetry.StartSpan(r.Context(), "delete_custom_command")
+	defer span.End()
+
+	name := r.PathValue("name")
+	telemetry.AddSpanAttributes(span, attribute.String("command.name", name))
+	channelID, err := rt.requestChannel(r.URL.Query().Get("channel"))
+	if err != nil {
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	if err := rt.Actions.DeleteCustomCommand(channelID, name); err != nil {
+		rt.Log.Error("Could not delete custom command "+name, err)
+		telemetry.RecordError(span, err)
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	rt.Log.Info(fmt.Sprintf("[COMMAND: DELETED] !%s for channel %s", name, channelID))
+	w.WriteHeader(http.StatusNoContent)
+}
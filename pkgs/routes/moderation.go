@@ -0,0 +1,50 @@
+// Package routes handles the routes of the server
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/actions"
+)
+
+// defaultAuditLimit is how many audit entries are returned when ?limit= is not set
+const defaultAuditLimit = 50
+
+var errInvalidLimit = errors.New("limit must be a positive number")
+
+// moderationAudit is the response of ModerationAuditHandler
+type moderationAudit struct {
+	Channel string               `json:"channel"`
+	Total   int                  `json:"total"`
+	Entries []actions.AuditEntry `json:"entries"`
+}
+
+// ModerationAuditHandler lists the latest moderation actions of the ?channel= broadcaster ID, newest first.
+// ?limit= sets how many are returned, at most 500
+func (rt *Router) ModerationAuditHandler(w http.ResponseWriter, r *http.Request) {
+	channelID, err := rt.requestChannel(r.URL.Query().Get("channel"))
+	if err != nil {
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	limit := defaultAuditLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			writeJSON(w, http.StatusBadRequest, errInvalidLimit)
+			return
+		}
+	}
+	entries, err := rt.Actions.ModerationAudit(channelID, limit)
+	if err != nil {
+		rt.Log.Error("Could not read moderation audit log", err)
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, moderationAudit{
+		Channel: channelID,
+		Total:   len(entries),
+		Entries: entries,
+	})
+}
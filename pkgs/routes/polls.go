@@ -0,0 +1,122 @@
+// Package routes handles the routes of the server
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
+	"github.com/mvaldes14/twitch-bot/pkgs/subscriptions"
+	"github.com/mvaldes14/twitch-bot/pkgs/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	errorNoPoll       = errors.New("no poll has run on this channel")
+	errorNoPrediction = errors.New("no prediction has run on this channel")
+)
+
+// PollHandler responds to channel.poll.* events
+func (rt *Router) PollHandler(w http.ResponseWriter, r *http.Request) {
+	rt.serveEvent(w, r, rt.handlePoll)
+}
+
+// handlePoll processes a channel.poll.begin, progress or end notification
+func (rt *Router) handlePoll(ctx context.Context, channel config.Channel, body []byte) error {
+	_, span := telemetry.StartSpan(ctx, "handle_poll")
+	defer span.End()
+
+	var pollEvent subscriptions.PollEvent
+	if err := json.Unmarshal(body, &pollEvent); err != nil {
+		rt.Log.Error("Failed to unmarshal poll event payload", err)
+		telemetry.RecordError(span, err)
+		return err
+	}
+	eventType := pollEvent.Subscription.Type
+	rt.Log.Info(fmt.Sprintf("Received %s event for poll %q in channel %s", eventType, pollEvent.Event.Title, channel.ID))
+	telemetry.AddSpanAttributes(span,
+		attribute.String("poll.event", eventType),
+		attribute.String("poll.id", pollEvent.Event.ID),
+	)
+
+	if err := rt.Actions.PollUpdated(channel.ID, eventType, pollEvent.Event); err != nil {
+		rt.Log.Error("Failed to announce poll in chat", err)
+		telemetry.RecordError(span, err)
+		return transient(err)
+	}
+	return nil
+}
+
+// PredictionHandler responds to channel.prediction.* events
+func (rt *Router) PredictionHandler(w http.ResponseWriter, r *http.Request) {
+	rt.serveEvent(w, r, rt.handlePrediction)
+}
+
+// handlePrediction processes a channel.prediction.begin, progress, lock or end notification
+func (rt *Router) handlePrediction(ctx context.Context, channel config.Channel, body []byte) error {
+	_, span := telemetry.StartSpan(ctx, "handle_prediction")
+	defer span.End()
+
+	var predictionEvent subscriptions.PredictionEvent
+	if err := json.Unmarshal(body, &predictionEvent); err != nil {
+		rt.Log.Error("Failed to unmarshal prediction event payload", err)
+		telemetry.RecordError(span, err)
+		return err
+	}
+	eventType := predictionEvent.Subscription.Type
+	rt.Log.Info(fmt.Sprintf("Received %s event for prediction %q in channel %s", eventType, predictionEvent.Event.Title, channel.ID))
+	telemetry.AddSpanAttributes(span,
+		attribute.String("prediction.event", eventType),
+		attribute.String("prediction.id", predictionEvent.Event.ID),
+	)
+
+	if err := rt.Actions.PredictionUpdated(channel.ID, eventType, predictionEvent.Event); err != nil {
+		rt.Log.Error("Failed to announce prediction in chat", err)
+		telemetry.RecordError(span, err)
+		return transient(err)
+	}
+	return nil
+}
+
+// OverlayPollHandler returns the latest poll of the ?channel= broadcaster ID with its votes, for stream overlays
+func (rt *Router) OverlayPollHandler(w http.ResponseWriter, r *http.Request) {
+	channelID, err := rt.requestChannel(r.URL.Query().Get("channel"))
+	if err != nil {
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	poll, ok, err := rt.Actions.CurrentPoll(channelID)
+	if err != nil {
+		rt.Log.Error("Could not read poll for overlay", err)
+		writeJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorNoPoll)
+		return
+	}
+	writeJSON(w, http.StatusOK, poll)
+}
+
+// OverlayPredictionHandler returns the latest prediction of the ?channel= broadcaster ID with its outcomes, for stream overlays
+func (rt *Router) OverlayPredictionHandler(w http.ResponseWriter, r *http.Request) {
+	channelID, err := rt.requestChannel(r.URL.Query().Get("channel"))
+	if err != nil {
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	prediction, ok, err := rt.Actions.CurrentPrediction(channelID)
+	if err != nil {
+		rt.Log.Error("Could not read prediction for overlay", err)
+		writeJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorNoPrediction)
+		return
+	}
+	writeJSON(w, http.StatusOK, prediction)
+}
@@ -0,0 +1,111 @@
+// Package routes handles the routes of the server
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/actions"
+	"github.com/mvaldes14/twitch-bot/pkgs/telemetry"
+)
+
+// PresetRequest is the body for creating or updating a channel info preset
+type PresetRequest struct {
+	// Channel is the broadcaster ID owning the preset, defaults to the primary channel
+	Channel string `json:"channel"`
+	actions.Preset
+}
+
+// presetList is the response of ListPresetsHandler
+type presetList struct {
+	Channel string           `json:"channel"`
+	Total   int              `json:"total"`
+	Presets []actions.Preset `json:"presets"`
+}
+
+// ListPresetsHandler lists the configured and runtime presets of the ?channel= broadcaster ID
+func (rt *Router) ListPresetsHandler(w http.ResponseWriter, r *http.Request) {
+	channelID, err := rt.requestChannel(r.URL.Query().Get("channel"))
+	if err != nil {
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	presets, err := rt.Actions.ListPresets(channelID)
+	if err != nil {
+		rt.Log.Error("Could not list presets", err)
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	if presets == nil {
+		presets = []actions.Preset{}
+	}
+	writeJSON(w, http.StatusOK, presetList{
+		Channel: channelID,
+		Total:   len(presets),
+		Presets: presets,
+	})
+}
+
+// CreatePresetHandler adds a runtime preset
+func (rt *Router) CreatePresetHandler(w http.ResponseWriter, r *http.Request) {
+	_, span := telemetry.StartSpan(r.Context(), "create_preset")
+	defer span.End()
+
+	var req PresetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		telemetry.RecordError(span, err)
+		writeJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	rt.savePreset(w, req, http.StatusCreated, rt.Actions.CreatePreset)
+}
+
+// UpdatePresetHandler replaces the runtime preset named by the {name} path value
+func (rt *Router) UpdatePresetHandler(w http.ResponseWriter, r *http.Request) {
+	_, span := telemetry.StartSpan(r.Context(), "update_preset")
+	defer span.End()
+
+	var req PresetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		telemetry.RecordError(span, err)
+		writeJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	req.Name = r.PathValue("name")
+	rt.savePreset(w, req, http.StatusOK, rt.Actions.UpdatePreset)
+}
+
+// savePreset stores a preset with the given create or update action
+func (rt *Router) savePreset(w http.ResponseWriter, req PresetRequest, status int, save func(string, actions.Preset) (actions.Preset, error)) {
+	channelID, err := rt.requestChannel(req.Channel)
+	if err != nil {
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	preset, err := save(channelID, req.Preset)
+	if err != nil {
+		rt.Log.Error("Could not save preset "+req.Name, err)
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	rt.Log.Info(fmt.Sprintf("[PRESET: SAVED] %s for channel %s", preset.Name, channelID))
+	writeJSON(w, status, preset)
+}
+
+// DeletePresetHandler removes the runtime preset named by the {name} path value
+func (rt *Router) DeletePresetHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	channelID, err := rt.requestChannel(r.URL.Query().Get("channel"))
+	if err != nil {
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	if err := rt.Actions.DeletePreset(channelID, name); err != nil {
+		rt.Log.Error("Could not delete preset "+name, err)
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	rt.Log.Info(fmt.Sprintf("[PRESET: DELETED] %s for channel %s", name, channelID))
+	w.WriteHeader(http.StatusNoContent)
+}
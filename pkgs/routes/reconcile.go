@@ -0,0 +1,247 @@
+// Package routes defines all routes for handlers and functionality
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
+	"github.com/mvaldes14/twitch-bot/pkgs/subscriptions"
+	"github.com/mvaldes14/twitch-bot/pkgs/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// Reconcile actions
+	reconcileCreate   = "create"
+	reconcileDelete   = "delete"
+	reconcileRecreate = "recreate"
+)
+
+// ReconcileAction is a single change needed to match the desired subscriptions
+type ReconcileAction struct {
+	Action  string `json:"action"`
+	Type    string `json:"type"`
+	Channel string `json:"channel"`
+	ID      string `json:"id,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Target  string `json:"target,omitempty"`
+	Reason  string `json:"reason"`
+	Result  string `json:"result,omitempty"`
+}
+
+// ReconcilePlan lists the changes between the desired and the current subscriptions
+type ReconcilePlan struct {
+	DryRun  bool              `json:"dry_run"`
+	InSync  []string          `json:"in_sync"`
+	Actions []ReconcileAction `json:"actions"`
+}
+
+// PlanReconcile diffs the desired subscription types of every channel against the ones Twitch currently has
+func (rt *Router) PlanReconcile() (ReconcilePlan, error) {
+	plan := ReconcilePlan{DryRun: true, InSync: []string{}, Actions: []ReconcileAction{}}
+
+	// Websocket subscriptions can only be created once a session is open
+	if rt.Subs.Transport == config.TransportWebSocket && rt.currentSession() == "" {
+		return plan, errorNoSession
+	}
+
+	current, err := rt.Subs.GetSubscriptions()
+	if err != nil {
+		return plan, fmt.Errorf("failed to list current subscriptions: %w", err)
+	}
+	return rt.diffSubscriptions(current.Data), nil
+}
+
+// diffSubscriptions plans the actions that turn the current subscriptions into the desired ones
+func (rt *Router) diffSubscriptions(current []subscriptions.SubscriptionData) ReconcilePlan {
+	plan := ReconcilePlan{DryRun: true, InSync: []string{}, Actions: []ReconcileAction{}}
+
+	existing := map[string][]subscriptions.SubscriptionData{}
+	for _, sub := range current {
+		key := subscriptionKey(sub.Type, sub.Condition.BroadcasterUserID)
+		existing[key] = append(existing[key], sub)
+	}
+
+	// Iterate in a stable order so plans are easy to compare between runs
+	names := make([]string, 0, len(subscriptionTypes))
+	for name := range subscriptionTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, channel := range rt.Config.AllChannels() {
+		for _, name := range names {
+			subType := subscriptionTypes[name]
+			key := subscriptionKey(subType.Type, channel.ID)
+			wantTarget := rt.transportTarget(subType)
+
+			// Keep the first healthy subscription that already points at the right callback
+			keeper := ""
+			for _, sub := range existing[key] {
+				if sub.Healthy() && subscriptionTarget(sub) == wantTarget {
+					keeper = sub.ID
+					break
+				}
+			}
+			satisfied := keeper != ""
+
+			for _, sub := range existing[key] {
+				switch {
+				case sub.ID == keeper:
+					plan.InSync = append(plan.InSync, key)
+				case !sub.Healthy():
+					plan.Actions = append(plan.Actions, ReconcileAction{
+						Action: reconcileDelete, Type: sub.Type, Channel: channel.ID, ID: sub.ID, Status: sub.Status,
+						Reason: "subscription is in state " + sub.Status,
+					})
+				case satisfied:
+					plan.Actions = append(plan.Actions, ReconcileAction{
+						Action: reconcileDelete, Type: sub.Type, Channel: channel.ID, ID: sub.ID, Status: sub.Status,
+						Reason: "duplicate subscription",
+					})
+				default:
+					plan.Actions = append(plan.Actions, ReconcileAction{
+						Action: reconcileRecreate, Type: sub.Type, Channel: channel.ID, ID: sub.ID, Status: sub.Status, Target: wantTarget,
+						Reason: fmt.Sprintf("transport target changed from %s", subscriptionTarget(sub)),
+					})
+					satisfied = true
+				}
+			}
+			delete(existing, key)
+
+			if !satisfied {
+				plan.Actions = append(plan.Actions, ReconcileAction{
+					Action: reconcileCreate, Type: subType.Type, Channel: channel.ID, Target: wantTarget,
+					Reason: "subscription is missing",
+				})
+			}
+		}
+	}
+
+	// Types and channels we no longer manage are left alone unless they are broken
+	for _, subs := range existing {
+		for _, sub := range subs {
+			if !sub.Healthy() {
+				plan.Actions = append(plan.Actions, ReconcileAction{
+					Action: reconcileDelete, Type: sub.Type, Channel: sub.Condition.BroadcasterUserID, ID: sub.ID, Status: sub.Status,
+					Reason: "subscription is in state " + sub.Status,
+				})
+			}
+		}
+	}
+
+	return plan
+}
+
+// Reconcile applies the plan returned by PlanReconcile, recording the result of every action
+func (rt *Router) Reconcile(ctx context.Context) (ReconcilePlan, error) {
+	ctx, span := telemetry.StartSpan(ctx, "subscription.reconcile")
+	defer span.End()
+
+	plan, err := rt.PlanReconcile()
+	if err != nil {
+		telemetry.RecordError(span, err)
+		return plan, err
+	}
+	plan.DryRun = false
+
+	for i := range plan.Actions {
+		action := &plan.Actions[i]
+		if err := rt.applyReconcileAction(*action); err != nil {
+			rt.Log.Error(fmt.Sprintf("Reconcile %s failed for %s on channel %s", action.Action, action.Type, action.Channel), err)
+			telemetry.RecordError(span, err)
+			action.Result = "error: " + err.Error()
+			telemetry.IncrementReconcileAction(ctx, action.Action, "error")
+			continue
+		}
+		action.Result = "success"
+		telemetry.IncrementReconcileAction(ctx, action.Action, "success")
+	}
+
+	telemetry.AddSpanAttributes(span,
+		attribute.Int("reconcile.actions", len(plan.Actions)),
+		attribute.Int("reconcile.in_sync", len(plan.InSync)),
+	)
+	rt.Log.Info(fmt.Sprintf("Reconcile finished - %d in sync, %d actions", len(plan.InSync), len(plan.Actions)))
+	return plan, nil
+}
+
+// subscriptionKey identifies a subscription type on a channel, e.g. "channel.follow@1792311"
+func subscriptionKey(eventType, broadcasterID string) string {
+	return eventType + "@" + broadcasterID
+}
+
+func (rt *Router) applyReconcileAction(action ReconcileAction) error {
+	if action.Action == reconcileDelete || action.Action == reconcileRecreate {
+		rt.Log.Info(fmt.Sprintf("Reconcile: deleting %s subscription %s on channel %s (%s)", action.Type, action.ID, action.Channel, action.Reason))
+		if err := rt.Subs.DeleteSubscription(action.ID); err != nil && !errors.Is(err, subscriptions.ErrSubscriptionNotFound) {
+			return err
+		}
+	}
+	if action.Action == reconcileCreate || action.Action == reconcileRecreate {
+		subType, ok := subscriptionTypeFor(action.Type)
+		if !ok {
+			return fmt.Errorf("%w: %s", errorInvalidSbuscription, action.Type)
+		}
+		rt.Log.Info(fmt.Sprintf("Reconcile: creating %s subscription on channel %s (%s)", action.Type, action.Channel, action.Reason))
+		return rt.Subs.CreateSubscription(rt.GeneratePayload(action.Channel, subType))
+	}
+	return nil
+}
+
+// StartReconciler reconciles subscriptions once at startup and then on every configured interval.
+// The goroutine exits when the provided context is cancelled.
+func (rt *Router) StartReconciler(ctx context.Context) {
+	interval := rt.Config.EventSub.ReconcileInterval
+
+	go func() {
+		rt.Log.Info("Subscription reconciler started, checking every " + interval.String())
+		// Websocket subscriptions are reconciled as soon as the session welcome arrives
+		if rt.Subs.Transport != config.TransportWebSocket {
+			if _, err := rt.Reconcile(ctx); err != nil {
+				rt.Log.Error("Startup subscription reconcile failed", err)
+			}
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				rt.Log.Info("Subscription reconciler stopped")
+				return
+			case <-ticker.C:
+				if _, err := rt.Reconcile(ctx); err != nil {
+					rt.Log.Error("Periodic subscription reconcile failed", err)
+				}
+			}
+		}
+	}()
+}
+
+// ReconcileHandler shows the planned subscription changes on GET and applies them on POST
+func (rt *Router) ReconcileHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		plan ReconcilePlan
+		err  error
+	)
+	if r.Method == http.MethodPost {
+		plan, err = rt.Reconcile(r.Context())
+	} else {
+		plan, err = rt.PlanReconcile()
+	}
+	if err != nil {
+		rt.Log.Error("Could not reconcile subscriptions", err)
+		http.Error(w, "Could not reconcile subscriptions", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(plan)
+}
@@ -0,0 +1,169 @@
+package routes
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
+	"github.com/mvaldes14/twitch-bot/pkgs/subscriptions"
+)
+
+const testChannel = "100"
+
+// newReconcileRouter serves one channel over the given transport
+func newReconcileRouter(transport string) *Router {
+	rt := &Router{
+		Subs: &subscriptions.Subscription{Transport: transport},
+		Config: &config.Config{
+			Broadcaster: config.Channel{ID: testChannel},
+			EventSub:    config.EventSub{CallbackURL: "https://bot.example/"},
+		},
+	}
+	rt.sessionID = "session-new"
+	return rt
+}
+
+// healthySubscriptions returns an enabled subscription of every managed type delivering to the router
+func healthySubscriptions(rt *Router) []subscriptions.SubscriptionData {
+	var subs []subscriptions.SubscriptionData
+	for _, subType := range subscriptionTypes {
+		subs = append(subs, subscription(rt, subType.Type+"-ok", subType.Type, "enabled", rt.transportTarget(subType)))
+	}
+	return subs
+}
+
+func subscription(rt *Router, id, eventType, status, target string) subscriptions.SubscriptionData {
+	sub := subscriptions.SubscriptionData{ID: id, Type: eventType, Status: status}
+	sub.Condition.BroadcasterUserID = testChannel
+	sub.Transport.Method = rt.Subs.Transport
+	if rt.Subs.Transport == config.TransportWebSocket {
+		sub.Transport.SessionID = target
+	} else {
+		sub.Transport.Callback = target
+	}
+	return sub
+}
+
+// without drops the healthy subscription of an event type
+func without(subs []subscriptions.SubscriptionData, eventType string) []subscriptions.SubscriptionData {
+	var kept []subscriptions.SubscriptionData
+	for _, sub := range subs {
+		if sub.Type != eventType {
+			kept = append(kept, sub)
+		}
+	}
+	return kept
+}
+
+// summarize renders actions as "action type id" in a stable order
+func summarize(actions []ReconcileAction) []string {
+	summary := []string{}
+	for _, action := range actions {
+		summary = append(summary, action.Action+" "+action.Type+" "+action.ID)
+	}
+	sort.Strings(summary)
+	return summary
+}
+
+func TestDiffSubscriptions(t *testing.T) {
+	webhook := newReconcileRouter(config.TransportWebhook)
+	websocket := newReconcileRouter(config.TransportWebSocket)
+	follow := subscriptionTypes["follow"]
+
+	tests := []struct {
+		name        string
+		rt          *Router
+		current     []subscriptions.SubscriptionData
+		wantActions []string
+		wantInSync  int
+	}{
+		{
+			name:       "in sync",
+			rt:         webhook,
+			current:    healthySubscriptions(webhook),
+			wantInSync: len(subscriptionTypes),
+		},
+		{
+			name:        "missing subscription is created",
+			rt:          webhook,
+			current:     without(healthySubscriptions(webhook), "channel.follow"),
+			wantActions: []string{"create channel.follow "},
+			wantInSync:  len(subscriptionTypes) - 1,
+		},
+		{
+			name: "failed subscription is replaced",
+			rt:   webhook,
+			current: append(without(healthySubscriptions(webhook), "channel.follow"),
+				subscription(webhook, "follow-failed", "channel.follow", "notification_failures_exceeded", webhook.callbackFor(follow))),
+			wantActions: []string{"create channel.follow ", "delete channel.follow follow-failed"},
+			wantInSync:  len(subscriptionTypes) - 1,
+		},
+		{
+			name: "duplicate is deleted",
+			rt:   webhook,
+			current: append(healthySubscriptions(webhook),
+				subscription(webhook, "follow-dup", "channel.follow", "enabled", webhook.callbackFor(follow))),
+			wantActions: []string{"delete channel.follow follow-dup"},
+			wantInSync:  len(subscriptionTypes),
+		},
+		{
+			name: "changed callback is recreated",
+			rt:   webhook,
+			current: append(without(healthySubscriptions(webhook), "channel.follow"),
+				subscription(webhook, "follow-old", "channel.follow", "enabled", "https://old.example/follow")),
+			wantActions: []string{"recreate channel.follow follow-old"},
+			wantInSync:  len(subscriptionTypes) - 1,
+		},
+		{
+			name: "old websocket session is recreated",
+			rt:   websocket,
+			current: append(without(healthySubscriptions(websocket), "channel.follow"),
+				subscription(websocket, "follow-old", "channel.follow", "enabled", "session-old")),
+			wantActions: []string{"recreate channel.follow follow-old"},
+			wantInSync:  len(subscriptionTypes) - 1,
+		},
+		{
+			name: "unmanaged subscriptions are only deleted when broken",
+			rt:   webhook,
+			current: append(healthySubscriptions(webhook),
+				subscription(webhook, "other-ok", "channel.raid", "enabled", "https://bot.example/raid"),
+				subscription(webhook, "other-revoked", "channel.raid", "authorization_revoked", "https://bot.example/raid")),
+			wantActions: []string{"delete channel.raid other-revoked"},
+			wantInSync:  len(subscriptionTypes),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := tt.rt.diffSubscriptions(tt.current)
+			want := tt.wantActions
+			if want == nil {
+				want = []string{}
+			}
+			sort.Strings(want)
+			if got := summarize(plan.Actions); !reflect.DeepEqual(got, want) {
+				t.Errorf("actions = %q, want %q", got, want)
+			}
+			if len(plan.InSync) != tt.wantInSync {
+				t.Errorf("in sync = %d, want %d", len(plan.InSync), tt.wantInSync)
+			}
+			if !plan.DryRun {
+				t.Error("a plan must be a dry run until applied")
+			}
+		})
+	}
+}
+
+func TestDiffSubscriptionsCreatesEveryTypeForANewChannel(t *testing.T) {
+	rt := newReconcileRouter(config.TransportWebhook)
+	plan := rt.diffSubscriptions(nil)
+	if len(plan.Actions) != len(subscriptionTypes) {
+		t.Fatalf("actions = %d, want one create per type (%d)", len(plan.Actions), len(subscriptionTypes))
+	}
+	for _, action := range plan.Actions {
+		subType, ok := subscriptionTypeFor(action.Type)
+		if action.Action != reconcileCreate || !ok || action.Channel != testChannel || action.Target != rt.callbackFor(subType) {
+			t.Errorf("unexpected action %+v", action)
+		}
+	}
+}
@@ -2,17 +2,23 @@
 package routes
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strconv"
+	"sync"
 	"text/template"
 	"time"
 
 	"github.com/mvaldes14/twitch-bot/pkgs/actions"
 	"github.com/mvaldes14/twitch-bot/pkgs/cache"
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
+	"github.com/mvaldes14/twitch-bot/pkgs/locale"
 	"github.com/mvaldes14/twitch-bot/pkgs/notifications"
 	"github.com/mvaldes14/twitch-bot/pkgs/secrets"
 	"github.com/mvaldes14/twitch-bot/pkgs/spotify"
@@ -23,13 +29,105 @@ import (
 
 const (
 	adminToken = "ADMIN_TOKEN"
+
+	// EventSub webhook headers
+	eventSubMessageID        = "Twitch-Eventsub-Message-Id"
+	eventSubMessageTimestamp = "Twitch-Eventsub-Message-Timestamp"
+	eventSubMessageSignature = "Twitch-Eventsub-Message-Signature"
+	eventSubMessageType      = "Twitch-Eventsub-Message-Type"
+
+	maxEventSubBodyBytes = 1 << 20
+	// Twitch recommends rejecting deliveries older than 10 minutes; message IDs
+	// are remembered for the same window so retries inside it are dropped
+	eventSubReplayWindow = 10 * time.Minute
 )
 
 var (
 	errorInvalidSbuscription = errors.New("could not generate a valid subscription")
 	errorNoMusicPlaying      = errors.New("nothing is playing on spotify")
+	errorMissingSignature    = errors.New("eventsub request is missing signature headers")
+	errorInvalidSignature    = errors.New("eventsub request signature does not match")
+	errorStaleMessage        = errors.New("eventsub message timestamp is outside the replay window")
+	errorUnknownMessageType  = errors.New("unknown eventsub message type")
+	errorSubscriptionRevoked = errors.New("eventsub subscription revoked")
+	errorNoSession           = errors.New("no eventsub websocket session is connected yet")
+	errorUnknownChannel      = errors.New("event is for a channel this bot does not serve")
 )
 
+// subscriptionTypes are the EventSub subscriptions the bot knows how to create, keyed by request name
+var subscriptionTypes = map[string]subscriptions.SubscriptionType{
+	"chat": {
+		Name:    "chat",
+		Version: "1",
+		Type:    "channel.chat.message",
+	},
+	"follow": {
+		Name:    "follow",
+		Version: "2",
+		Type:    "channel.follow",
+	},
+	"subscription": {
+		Name:    "subscribe",
+		Version: "1",
+		Type:    "channel.subscribe",
+	},
+	"cheer": {
+		Name:    "cheer",
+		Version: "1",
+		Type:    "channel.cheer",
+	},
+	"reward": {
+		Name:    "reward",
+		Version: "1",
+		Type:    "channel.channel_points_custom_reward_redemption.add",
+	},
+	"streamon": {
+		Name:    "stream",
+		Version: "1",
+		Type:    "stream.online",
+	},
+	"streamoff": {
+		Name:    "stream",
+		Version: "1",
+		Type:    "stream.offline",
+	},
+	"pollbegin": {
+		Name:    "poll",
+		Version: "1",
+		Type:    "channel.poll.begin",
+	},
+	"pollprogress": {
+		Name:    "poll",
+		Version: "1",
+		Type:    "channel.poll.progress",
+	},
+	"pollend": {
+		Name:    "poll",
+		Version: "1",
+		Type:    "channel.poll.end",
+	},
+	"predictionbegin": {
+		Name:    "prediction",
+		Version: "1",
+		Type:    "channel.prediction.begin",
+	},
+	"predictionprogress": {
+		Name:    "prediction",
+		Version: "1",
+		Type:    "channel.prediction.progress",
+	},
+	"predictionlock": {
+		Name:    "prediction",
+		Version: "1",
+		Type:    "channel.prediction.lock",
+	},
+	"predictionend": {
+		Name:    "prediction",
+		Version: "1",
+		Type:    "channel.prediction.end",
+	},
+}
+
 // RequestJSON represents a JSON HTTP request
 type RequestJSON struct {
 	Method  string
@@ -47,26 +145,38 @@ type SongData struct {
 
 // Router is the struct that handles all routes
 type Router struct {
-	Subs            *subscriptions.Subscription
-	Secrets         *secrets.SecretService
-	Actions         *actions.Actions
-	Spotify         *spotify.Spotify
-	Log             *telemetry.CustomLogger
-	Notification    *notifications.NotificationService
-	streamStartTime time.Time
-	Cache           *cache.Service
+	Subs         *subscriptions.Subscription
+	Secrets      *secrets.SecretService
+	Actions      *actions.Actions
+	Spotify      *spotify.Spotify
+	Log          *telemetry.CustomLogger
+	Notification *notifications.NotificationService
+	Cache        *cache.Service
+	Config       *config.Config
+	// sessionID is the active EventSub websocket session when using the websocket transport
+	sessionID string
+	sessionMu sync.RWMutex
 }
 
 // SubscriptionTypeRequest is the struct for generating new subscriptions
 type SubscriptionTypeRequest struct {
 	Type string `json:"type"`
+	// Channel is the broadcaster ID to subscribe to, defaults to the primary channel
+	Channel string `json:"channel"`
+}
+
+// SubscriptionSelector picks the subscriptions to delete, empty fields match everything
+type SubscriptionSelector struct {
+	Status string `json:"status"`
+	Type   string `json:"type"`
 }
 
 // NewRouter creates a new router
-func NewRouter(subs *subscriptions.Subscription, secretService *secrets.SecretService) *Router {
-	actionsService := actions.NewActions(secretService)
+func NewRouter(subs *subscriptions.Subscription, secretService *secrets.SecretService, cfg *config.Config) *Router {
+	actionsService := actions.NewActions(secretService, cfg)
 	spotifyClient := spotify.NewSpotify()
-	notify := notifications.NewNotificationService()
+	// Clips from chat go to Discord through the same notification service as the router
+	notify := actionsService.Notification
 	logger := telemetry.NewLogger("router")
 	cacheService := cache.NewCacheService()
 	return &Router{
@@ -77,6 +187,7 @@ func NewRouter(subs *subscriptions.Subscription, secretService *secrets.SecretSe
 		Spotify:      spotifyClient,
 		Notification: notify,
 		Cache:        cacheService,
+		Config:       cfg,
 	}
 }
 
@@ -114,13 +225,110 @@ func (rt *Router) CheckAuthAdmin(next http.Handler) http.Handler {
 	})
 }
 
-// MiddleWareRoute checks for headers in all requests
+// VerifySignature rejects EventSub callbacks that are not signed with our webhook secret.
+// The body is read once to compute the HMAC and restored so handlers can decode it.
+func (rt *Router) VerifySignature(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := telemetry.StartSpan(r.Context(), "eventsub_verify_signature")
+		defer span.End()
+
+		messageID := r.Header.Get(eventSubMessageID)
+		timestamp := r.Header.Get(eventSubMessageTimestamp)
+		signature := r.Header.Get(eventSubMessageSignature)
+		if messageID == "" || timestamp == "" || signature == "" {
+			rt.Log.Error("Rejecting EventSub request for "+r.URL.Path, errorMissingSignature)
+			telemetry.RecordError(span, errorMissingSignature)
+			telemetry.IncrementEventSubRejected(ctx, "missing_signature")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxEventSubBodyBytes))
+		if err != nil {
+			rt.Log.Error("Failed to read EventSub request body", err)
+			telemetry.RecordError(span, err)
+			telemetry.IncrementEventSubRejected(ctx, "unreadable_body")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = r.Body.Close()
+
+		if !validSignature(rt.Config.EventSub.Secret, messageID, timestamp, body, signature) {
+			rt.Log.Error("Rejecting EventSub request for "+r.URL.Path, errorInvalidSignature)
+			telemetry.RecordError(span, errorInvalidSignature)
+			telemetry.IncrementEventSubRejected(ctx, "invalid_signature")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		telemetry.AddSpanAttributes(span,
+			attribute.String("eventsub.message_id", messageID),
+		)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RejectReplays drops EventSub deliveries that are too old or were already processed.
+// Duplicates are acknowledged with a 2xx so Twitch stops retrying, without running the handler again.
+// A delivery whose handler fails is forgotten again, so the retry Twitch sends is processed.
+func (rt *Router) RejectReplays(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := telemetry.StartSpan(r.Context(), "eventsub_reject_replays")
+		defer span.End()
+
+		sentAt, err := time.Parse(time.RFC3339Nano, r.Header.Get(eventSubMessageTimestamp))
+		if err != nil || time.Since(sentAt) > eventSubReplayWindow {
+			rt.Log.Error("Rejecting EventSub message "+r.Header.Get(eventSubMessageID), errorStaleMessage)
+			telemetry.RecordError(span, errorStaleMessage)
+			telemetry.IncrementEventSubRejected(ctx, "stale_timestamp")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		// Challenge retries must always be answered so the subscription can be verified
+		if r.Header.Get(eventSubMessageType) == subscriptions.MessageTypeVerification {
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		messageID := r.Header.Get(eventSubMessageID)
+		if !rt.firstDelivery(messageID) {
+			rt.Log.Info(fmt.Sprintf("[EVENTSUB: DUPLICATE] Skipping already processed message %s", messageID))
+			telemetry.AddSpanAttributes(span, attribute.Bool("eventsub.duplicate", true))
+			telemetry.IncrementEventSubRejected(ctx, "duplicate")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				rt.releaseDelivery(messageID)
+				panic(recovered)
+			}
+		}()
+		next.ServeHTTP(rw, r.WithContext(ctx))
+		if rw.statusCode >= http.StatusInternalServerError {
+			rt.releaseDelivery(messageID)
+		}
+	})
+}
+
+// MiddleWareRoute dispatches EventSub deliveries by message type.
+// Challenges and revocations are answered here, only notifications reach the event handlers.
 func (rt *Router) MiddleWareRoute(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Header.Get("Twitch-Eventsub-Message-Type") == "webhook_callback_verification" {
+		switch messageType := r.Header.Get(eventSubMessageType); messageType {
+		case subscriptions.MessageTypeVerification:
 			rt.respondToChallenge(w, r)
-		} else {
+		case subscriptions.MessageTypeRevocation:
+			rt.respondToRevocation(w, r)
+		case subscriptions.MessageTypeNotification:
 			next.ServeHTTP(w, r)
+		default:
+			rt.Log.Error("Unknown EventSub message type: "+messageType, errorUnknownMessageType)
+			telemetry.IncrementEventSubRejected(r.Context(), "unknown_message_type")
+			w.WriteHeader(http.StatusBadRequest)
 		}
 	})
 }
@@ -154,13 +362,57 @@ func (rw *responseWriter) WriteHeader(code int) {
 }
 
 // HANDLERS
+// eventHandler processes the notification body of an event for the channel it belongs to.
+// An error means the event was not handled, Twitch only delivers it again when the error is retryable
+type eventHandler func(ctx context.Context, channel config.Channel, body []byte) error
+
+// serveEvent reads a webhook notification body and hands it to the event handler.
+// Only a retryable failure is answered with a 500 so Twitch delivers the event again. Other failures would fail
+// the same way on every retry until Twitch revokes the subscription, so they are acknowledged and dropped
+func (rt *Router) serveEvent(w http.ResponseWriter, r *http.Request, handle eventHandler) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		rt.Log.Error("Failed to read event request body for "+r.URL.Path, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+	if err := rt.dispatch(r.Context(), body, handle); err != nil {
+		if retryable(err) {
+			rt.Log.Error("Event for "+r.URL.Path+" failed, asking Twitch to retry", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		rt.Log.Error("Dropping event for "+r.URL.Path+", a retry would fail the same way", err)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// dispatch routes a notification to its channel using the broadcaster_user_id of its subscription condition.
+// Events for channels that are not configured are dropped.
+func (rt *Router) dispatch(ctx context.Context, body []byte, handle eventHandler) error {
+	var envelope subscriptions.EventSubMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		rt.Log.Error("Failed to unmarshal event envelope", err)
+		return err
+	}
+	channelID := envelope.Subscription.Condition.BroadcasterUserID
+	channel, ok := rt.Config.Channel(channelID)
+	if !ok {
+		rt.Log.Error(fmt.Sprintf("Dropping %s event for channel %q", envelope.Subscription.Type, channelID), errorUnknownChannel)
+		telemetry.IncrementEventSubRejected(ctx, "unknown_channel")
+		return nil
+	}
+	return handle(ctx, channel, body)
+}
+
 // respondToChallenge responds to challenge for a subscription on twitch eventsub
 func (rt *Router) respondToChallenge(w http.ResponseWriter, r *http.Request) {
 	_, span := telemetry.StartSpan(r.Context(), "eventsub_challenge_verification")
 	defer span.End()
 
 	rt.Log.Info("Responding to challenge")
-	var challengeResponse subscriptions.SubscribeEvent
+	var challengeResponse subscriptions.EventSubMessage
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		rt.Log.Error("Failed to read challenge request body", err)
@@ -194,21 +446,141 @@ func (rt *Router) respondToChallenge(w http.ResponseWriter, r *http.Request) {
 	)
 }
 
-// DeleteHandler deletes all subscriptions
-func (rt *Router) DeleteHandler(w http.ResponseWriter, _ *http.Request) {
-	subsList, err := rt.Subs.GetSubscriptions()
+// respondToRevocation acknowledges a webhook revocation message and hands it to handleRevocation
+func (rt *Router) respondToRevocation(w http.ResponseWriter, r *http.Request) {
+	var revocation subscriptions.EventSubMessage
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		rt.Log.Error("Could not get subscriptions", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		rt.Log.Error("Failed to read revocation request body", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := json.Unmarshal(body, &revocation); err != nil {
+		rt.Log.Error("Failed to unmarshal revocation payload", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Twitch only needs an acknowledgement, follow-up work happens after responding
+	w.WriteHeader(http.StatusNoContent)
+	rt.handleRevocation(r.Context(), revocation.Subscription)
+}
+
+// handleRevocation handles Twitch revoking a subscription: the reason is logged and counted,
+// an alert is sent and, when enabled, the subscription is created again
+func (rt *Router) handleRevocation(ctx context.Context, sub subscriptions.SubscriptionData) {
+	ctx, span := telemetry.StartSpan(ctx, "eventsub_revocation")
+	defer span.End()
+
+	reason := sub.Status
+	rt.Log.Error(fmt.Sprintf("Subscription revoked - ID: %s, Type: %s, Reason: %s", sub.ID, sub.Type, reason), errorSubscriptionRevoked)
+	telemetry.RecordError(span, errorSubscriptionRevoked)
+	telemetry.AddSpanAttributes(span,
+		attribute.String("revocation.subscription_id", sub.ID),
+		attribute.String("revocation.subscription_type", sub.Type),
+		attribute.String("revocation.reason", reason),
+	)
+	telemetry.IncrementSubscriptionRevoked(ctx, sub.Type, reason)
+
+	if err := rt.Notification.SendNotification(fmt.Sprintf("Twitch revoked the %s subscription: %s", sub.Type, reason)); err != nil {
+		rt.Log.Error("Failed to send revocation alert", err)
+	}
+
+	if !rt.Config.EventSub.RecreateOnRevocation {
+		return
+	}
+	// Recreating cannot succeed until the user authorizes the app again
+	if reason == subscriptions.RevokedAuthorization || reason == subscriptions.RevokedUserRemoved {
+		rt.Log.Info(fmt.Sprintf("Not recreating %s subscription, revocation reason %s needs manual action", sub.Type, reason))
+		return
+	}
+	subType, ok := subscriptionTypeFor(sub.Type)
+	if !ok {
+		rt.Log.Info("No known subscription type to recreate for " + sub.Type)
+		return
+	}
+	channelID := sub.Condition.BroadcasterUserID
+	if _, ok := rt.Config.Channel(channelID); !ok {
+		rt.Log.Info(fmt.Sprintf("Not recreating %s subscription, channel %s is no longer configured", sub.Type, channelID))
 		return
 	}
-	err = rt.Subs.DeleteSubscriptions(subsList)
+	if err := rt.Subs.CreateSubscription(rt.GeneratePayload(channelID, subType)); err != nil {
+		rt.Log.Error("Failed to recreate revoked subscription "+sub.Type, err)
+		telemetry.RecordError(span, err)
+		return
+	}
+	rt.Log.Info("Recreated revoked subscription " + sub.Type)
+}
+
+// DeleteHandler deletes the subscriptions matching an optional {"type": "...", "status": "..."} body.
+// An empty body deletes every subscription. Each deletion is reported individually.
+func (rt *Router) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	var selector SubscriptionSelector
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		rt.Log.Error("Could not read delete payload", err)
+		http.Error(w, "Could not read payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &selector); err != nil {
+			rt.Log.Error("Could not unmarshal delete payload", err)
+			http.Error(w, "Could not unmarshal payload", http.StatusBadRequest)
+			return
+		}
+	}
+
+	subsList, err := rt.Subs.ListSubscriptions(subscriptions.SubscriptionFilter(selector))
 	if err != nil {
-		rt.Log.Error("Could not delete subscriptions", err)
+		rt.Log.Error("Could not get subscriptions", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
+
+	results := rt.Subs.DeleteSubscriptions(subsList)
+	failed := 0
+	for _, result := range results {
+		if !result.Deleted {
+			failed++
+			rt.Log.Error(fmt.Sprintf("Could not delete subscription %s (%s)", result.ID, result.Type), errors.New(result.Error))
+		}
+	}
+
+	status := http.StatusOK
+	if failed > 0 {
+		status = http.StatusInternalServerError
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"deleted": len(results) - failed,
+		"failed":  failed,
+		"results": results,
+	})
+}
+
+// DeleteSubscriptionHandler deletes a single subscription by the {id} path value
+func (rt *Router) DeleteSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	result := subscriptions.DeletionResult{ID: id, Deleted: true}
+	status := http.StatusOK
+
+	if err := rt.Subs.DeleteSubscription(id); err != nil {
+		rt.Log.Error("Could not delete subscription "+id, err)
+		result.Deleted = false
+		result.Error = err.Error()
+		status = http.StatusInternalServerError
+		if errors.Is(err, subscriptions.ErrSubscriptionNotFound) {
+			status = http.StatusNotFound
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(result)
 }
 
 // HealthHandler returns a healthy message
@@ -217,32 +589,36 @@ func (rt *Router) HealthHandler(w http.ResponseWriter, _ *http.Request) {
 }
 
 // ListHandler returns the current subscription list
-func (rt *Router) ListHandler(w http.ResponseWriter, _ *http.Request) {
-	subsList, err := rt.Subs.GetSubscriptions()
+// Supports ?status= and ?type= filters and includes a cost and status summary
+func (rt *Router) ListHandler(w http.ResponseWriter, r *http.Request) {
+	filter := subscriptions.SubscriptionFilter{
+		Status: r.URL.Query().Get("status"),
+		Type:   r.URL.Query().Get("type"),
+	}
+	subsList, err := rt.Subs.ListSubscriptions(filter)
 	if err != nil {
 		rt.Log.Error("Could not get subscriptions", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-
-	if subsList.Total == 0 {
-		rt.Log.Info("No subscriptions found")
-		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"total": 0,
-			"data":  []interface{}{},
-		})
-		return
-	}
-
-	rt.Log.Info(fmt.Sprintf("Current Subscription List: %d", subsList.Total))
+	summary := subsList.Summarize()
+	rt.Log.Info(fmt.Sprintf("Current Subscription List: %d (cost %d/%d, failing %d)", subsList.Total, summary.TotalCost, summary.MaxTotalCost, summary.Failing))
 	for _, sub := range subsList.Data {
 		rt.Log.Info(fmt.Sprintf("Status: %s, Type: %s, ID: %s", sub.Status, sub.Type, sub.ID))
 	}
 
-	_ = json.NewEncoder(w).Encode(subsList)
+	data := subsList.Data
+	if data == nil {
+		data = []subscriptions.SubscriptionData{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(subscriptions.SubscriptionList{
+		Total:   len(data),
+		Summary: summary,
+		Data:    data,
+	})
 }
 
 // CreateHandler creates a subscription based on the parameter
@@ -269,50 +645,23 @@ func (rt *Router) CreateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rt.Log.Info(fmt.Sprintf("Creating subscription for type: %s", requestTypeString.Type))
+	if requestTypeString.Channel == "" {
+		requestTypeString.Channel = rt.Config.Broadcaster.ID
+	}
+	if _, ok := rt.Config.Channel(requestTypeString.Channel); !ok {
+		rt.Log.Error("Subscription requested for unknown channel: "+requestTypeString.Channel, errorUnknownChannel)
+		telemetry.RecordError(span, errorUnknownChannel)
+		http.Error(w, "Unknown channel", http.StatusBadRequest)
+		return
+	}
+
+	rt.Log.Info(fmt.Sprintf("Creating subscription for type: %s, channel: %s", requestTypeString.Type, requestTypeString.Channel))
 
 	telemetry.AddSpanAttributes(span,
 		attribute.String("subscription.type_requested", requestTypeString.Type),
+		attribute.String("subscription.channel_id", requestTypeString.Channel),
 	)
 
-	subscriptionTypes := map[string]subscriptions.SubscriptionType{
-		"chat": {
-			Name:    "chat",
-			Version: "1",
-			Type:    "channel.chat.message",
-		},
-		"follow": {
-			Name:    "follow",
-			Version: "2",
-			Type:    "channel.follow",
-		},
-		"subscription": {
-			Name:    "subscribe",
-			Version: "1",
-			Type:    "channel.subscribe",
-		},
-		"cheer": {
-			Name:    "cheer",
-			Version: "1",
-			Type:    "channel.cheer",
-		},
-		"reward": {
-			Name:    "reward",
-			Version: "1",
-			Type:    "channel.channel_points_custom_reward_redemption.add",
-		},
-		"streamon": {
-			Name:    "stream",
-			Version: "1",
-			Type:    "stream.online",
-		},
-		"streamoff": {
-			Name:    "stream",
-			Version: "1",
-			Type:    "stream.offline",
-		},
-	}
-
 	if subTypeConfig, ok := subscriptionTypes[requestTypeString.Type]; ok {
 		telemetry.AddSpanAttributes(span,
 			attribute.String("subscription.config_name", subTypeConfig.Name),
@@ -320,7 +669,7 @@ func (rt *Router) CreateHandler(w http.ResponseWriter, r *http.Request) {
 			attribute.String("subscription.config_type", subTypeConfig.Type),
 		)
 
-		payload := rt.GeneratePayload(subTypeConfig)
+		payload := rt.GeneratePayload(requestTypeString.Channel, subTypeConfig)
 
 		if err := rt.Subs.CreateSubscription(payload); err != nil {
 			rt.Log.Error(fmt.Sprintf("Failed to create subscription for type: %s", requestTypeString.Type), err)
@@ -350,26 +699,23 @@ func (rt *Router) CreateHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // ChatHandler responds to chat messages
-func (rt *Router) ChatHandler(_ http.ResponseWriter, r *http.Request) {
-	ctx, span := telemetry.StartSpan(r.Context(), "handle_chat_message")
+func (rt *Router) ChatHandler(w http.ResponseWriter, r *http.Request) {
+	rt.serveEvent(w, r, rt.handleChat)
+}
+
+// handleChat processes a channel.chat.message notification
+func (rt *Router) handleChat(ctx context.Context, _ config.Channel, body []byte) error {
+	ctx, span := telemetry.StartSpan(ctx, "handle_chat_message")
 	defer span.End()
 
 	rt.Log.Info("Received chat message event")
 
 	telemetry.IncrementChatMessageCount(ctx)
 	var chatEvent subscriptions.ChatMessageEvent
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		rt.Log.Error("Failed to read chat event request body", err)
-		telemetry.RecordError(span, err)
-		return
-	}
-	defer r.Body.Close()
-
 	if err := json.Unmarshal(body, &chatEvent); err != nil {
 		rt.Log.Error("Failed to unmarshal chat event payload", err)
 		telemetry.RecordError(span, err)
-		return
+		return err
 	}
 
 	rt.Log.Info(fmt.Sprintf("Processing chat message from user: %s, message: %s", chatEvent.Event.ChatterUserName, chatEvent.Event.Message.Text))
@@ -380,32 +726,35 @@ func (rt *Router) ChatHandler(_ http.ResponseWriter, r *http.Request) {
 		attribute.String("chat.message", chatEvent.Event.Message.Text),
 	)
 
+	// Messages removed by moderation never run a command
+	if rt.Actions.Moderate(chatEvent) {
+		telemetry.AddSpanAttributes(span, attribute.Bool("chat.moderated", true))
+		return nil
+	}
+
 	//	Send to parser to respond
 	rt.Actions.ParseMessage(chatEvent)
 	rt.Log.Info(fmt.Sprintf("Successfully processed chat message from: %s", chatEvent.Event.ChatterUserName))
+	return nil
 }
 
 // FollowHandler responds to follow events
-func (rt *Router) FollowHandler(_ http.ResponseWriter, r *http.Request) {
-	ctx, span := telemetry.StartSpan(r.Context(), "handle_follow")
+func (rt *Router) FollowHandler(w http.ResponseWriter, r *http.Request) {
+	rt.serveEvent(w, r, rt.handleFollow)
+}
+
+// handleFollow processes a channel.follow notification
+func (rt *Router) handleFollow(ctx context.Context, channel config.Channel, body []byte) error {
+	ctx, span := telemetry.StartSpan(ctx, "handle_follow")
 	defer span.End()
 
 	rt.Log.Info("Received follow event")
 
-	telemetry.IncrementFollowCount(ctx)
 	var followEventResponse subscriptions.FollowEvent
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		rt.Log.Error("Failed to read follow event request body", err)
-		telemetry.RecordError(span, err)
-		return
-	}
-	defer r.Body.Close()
-
 	if err := json.Unmarshal(body, &followEventResponse); err != nil {
 		rt.Log.Error("Failed to unmarshal follow event payload", err)
 		telemetry.RecordError(span, err)
-		return
+		return err
 	}
 
 	rt.Log.Info(fmt.Sprintf("New follower: %s", followEventResponse.Event.UserName))
@@ -414,36 +763,37 @@ func (rt *Router) FollowHandler(_ http.ResponseWriter, r *http.Request) {
 		attribute.String("follow.user", followEventResponse.Event.UserName),
 	)
 
-	// Send to chat
-	if err := rt.Actions.SendMessage(fmt.Sprintf("Gracias por el follow: %v", followEventResponse.Event.UserName)); err != nil {
+	// Send to chat, the follow is counted once Twitch will not deliver it again
+	err := transient(rt.Actions.SendMessage(channel.ID, rt.Actions.Translate(channel.ID, locale.Follow, "{user}", followEventResponse.Event.UserName)))
+	if !retryable(err) {
+		telemetry.IncrementFollowCount(ctx)
+	}
+	if err != nil {
 		rt.Log.Error("Failed to send follow thank you message to chat", err)
 		telemetry.RecordError(span, err)
-		return
+		return err
 	}
 	rt.Log.Info(fmt.Sprintf("Successfully processed follow from: %s", followEventResponse.Event.UserName))
+	return nil
 }
 
 // SubHandler responds to subscription events
-func (rt *Router) SubHandler(_ http.ResponseWriter, r *http.Request) {
-	ctx, span := telemetry.StartSpan(r.Context(), "handle_subscription")
+func (rt *Router) SubHandler(w http.ResponseWriter, r *http.Request) {
+	rt.serveEvent(w, r, rt.handleSub)
+}
+
+// handleSub processes a channel.subscribe notification
+func (rt *Router) handleSub(ctx context.Context, channel config.Channel, body []byte) error {
+	ctx, span := telemetry.StartSpan(ctx, "handle_subscription")
 	defer span.End()
 
 	rt.Log.Info("Received subscription event")
 
-	telemetry.IncrementSubscriptionCount(ctx)
 	var subEventResponse subscriptions.SubscriptionEvent
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		rt.Log.Error("Failed to read subscription event request body", err)
-		telemetry.RecordError(span, err)
-		return
-	}
-	defer r.Body.Close()
-
 	if err := json.Unmarshal(body, &subEventResponse); err != nil {
 		rt.Log.Error("Failed to unmarshal subscription event payload", err)
 		telemetry.RecordError(span, err)
-		return
+		return err
 	}
 
 	rt.Log.Info(fmt.Sprintf("New subscriber: %s", subEventResponse.Event.UserName))
@@ -452,36 +802,37 @@ func (rt *Router) SubHandler(_ http.ResponseWriter, r *http.Request) {
 		attribute.String("subscription.user", subEventResponse.Event.UserName),
 	)
 
-	// send to chat
-	if err := rt.Actions.SendMessage(fmt.Sprintf("Gracias por el sub: %v", subEventResponse.Event.UserName)); err != nil {
+	// send to chat, the subscription is counted once Twitch will not deliver it again
+	err := transient(rt.Actions.SendMessage(channel.ID, rt.Actions.Translate(channel.ID, locale.Sub, "{user}", subEventResponse.Event.UserName)))
+	if !retryable(err) {
+		telemetry.IncrementSubscriptionCount(ctx)
+	}
+	if err != nil {
 		rt.Log.Error("Failed to send subscription thank you message to chat", err)
 		telemetry.RecordError(span, err)
-		return
+		return err
 	}
 	rt.Log.Info(fmt.Sprintf("Successfully processed subscription from: %s", subEventResponse.Event.UserName))
+	return nil
 }
 
 // CheerHandler responds to cheer events
-func (rt *Router) CheerHandler(_ http.ResponseWriter, r *http.Request) {
-	ctx, span := telemetry.StartSpan(r.Context(), "handle_cheer")
+func (rt *Router) CheerHandler(w http.ResponseWriter, r *http.Request) {
+	rt.serveEvent(w, r, rt.handleCheer)
+}
+
+// handleCheer processes a channel.cheer notification
+func (rt *Router) handleCheer(ctx context.Context, channel config.Channel, body []byte) error {
+	ctx, span := telemetry.StartSpan(ctx, "handle_cheer")
 	defer span.End()
 
 	rt.Log.Info("Received cheer event")
 
-	telemetry.IncrementCheerCount(ctx)
 	var cheerEventResponse subscriptions.CheerEvent
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		rt.Log.Error("Failed to read cheer event request body", err)
-		telemetry.RecordError(span, err)
-		return
-	}
-	defer r.Body.Close()
-
 	if err := json.Unmarshal(body, &cheerEventResponse); err != nil {
 		rt.Log.Error("Failed to unmarshal cheer event payload", err)
 		telemetry.RecordError(span, err)
-		return
+		return err
 	}
 
 	rt.Log.Info(fmt.Sprintf("Cheer received from: %s, bits: %d", cheerEventResponse.Event.UserName, cheerEventResponse.Event.Bits))
@@ -492,35 +843,42 @@ func (rt *Router) CheerHandler(_ http.ResponseWriter, r *http.Request) {
 	)
 
 	// send to chat
-	if err := rt.Actions.SendMessage(fmt.Sprintf("Gracias por los bits: %v", cheerEventResponse.Event.UserName)); err != nil {
+	thanks := rt.Actions.Translate(channel.ID, locale.Cheer,
+		"{user}", cheerEventResponse.Event.UserName,
+		"{bits}", strconv.Itoa(cheerEventResponse.Event.Bits),
+	)
+	// The cheer is counted once Twitch will not deliver it again
+	err := transient(rt.Actions.SendMessage(channel.ID, thanks))
+	if !retryable(err) {
+		telemetry.IncrementCheerCount(ctx)
+	}
+	if err != nil {
 		rt.Log.Error("Failed to send cheer thank you message to chat", err)
 		telemetry.RecordError(span, err)
-		return
+		return err
 	}
 	rt.Log.Info(fmt.Sprintf("Successfully processed cheer from: %s", cheerEventResponse.Event.UserName))
+	return nil
 }
 
 // RewardHandler responds to reward events
-func (rt *Router) RewardHandler(_ http.ResponseWriter, r *http.Request) {
-	ctx, span := telemetry.StartSpan(r.Context(), "handle_reward")
+func (rt *Router) RewardHandler(w http.ResponseWriter, r *http.Request) {
+	rt.serveEvent(w, r, rt.handleReward)
+}
+
+// handleReward processes a channel points reward redemption notification
+func (rt *Router) handleReward(ctx context.Context, channel config.Channel, body []byte) error {
+	ctx, span := telemetry.StartSpan(ctx, "handle_reward")
 	defer span.End()
 
 	rt.Log.Info("Received reward redemption event")
 
 	telemetry.IncrementRewardCount(ctx)
 	var rewardEventResponse subscriptions.RewardEvent
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		rt.Log.Error("Failed to read reward event request body", err)
-		telemetry.RecordError(span, err)
-		return
-	}
-	defer r.Body.Close()
-
 	if err := json.Unmarshal(body, &rewardEventResponse); err != nil {
 		rt.Log.Error("Failed to unmarshal reward event payload", err)
 		telemetry.RecordError(span, err)
-		return
+		return err
 	}
 
 	rt.Log.Info(fmt.Sprintf("Reward redeemed by: %s, reward: %s", rewardEventResponse.Event.UserName, rewardEventResponse.Event.Reward.Title))
@@ -535,7 +893,7 @@ func (rt *Router) RewardHandler(_ http.ResponseWriter, r *http.Request) {
 		if err := rt.Spotify.NextSong(); err != nil {
 			rt.Log.Error("Failed to skip to next song", err)
 			telemetry.RecordError(span, err)
-			return
+			return err
 		}
 		rt.Log.Info("Successfully skipped to next song")
 	}
@@ -546,7 +904,7 @@ func (rt *Router) RewardHandler(_ http.ResponseWriter, r *http.Request) {
 		if err := rt.Spotify.AddToPlaylist(spotifyURL); err != nil {
 			rt.Log.Error("Failed to add song to playlist", err)
 			telemetry.RecordError(span, err)
-			return
+			return err
 		}
 		rt.Log.Info(fmt.Sprintf("Successfully added song to playlist: %s", spotifyURL))
 	}
@@ -555,12 +913,22 @@ func (rt *Router) RewardHandler(_ http.ResponseWriter, r *http.Request) {
 		if err := rt.Spotify.DeleteSongPlaylist(); err != nil {
 			rt.Log.Error("Failed to reset playlist", err)
 			telemetry.RecordError(span, err)
-			return
+			return err
 		}
 		rt.Log.Info("Successfully reset playlist")
 	}
+	if rewardEventResponse.Event.Reward.Title == "Clip It" {
+		rt.Log.Info("Processing Clip It reward")
+		if err := rt.Actions.ClipReward(ctx, channel.ID, rewardEventResponse.Event.UserName); err != nil {
+			rt.Log.Error("Failed to create clip", err)
+			telemetry.RecordError(span, err)
+			return transient(err)
+		}
+		rt.Log.Info("Clip requested, the link is posted once Twitch finishes it")
+	}
 
 	rt.Log.Info(fmt.Sprintf("Successfully processed reward from: %s", rewardEventResponse.Event.UserName))
+	return nil
 }
 
 // TestHandler is used to test if the bot is responding to messages
@@ -573,33 +941,52 @@ func (rt *Router) TestHandler(_ http.ResponseWriter, _ *http.Request) {
 
 // StreamOnlineHandler sends a message to discord
 // Validates ADMIN_TOKEN environment variable exists before making requests
-func (rt *Router) StreamOnlineHandler(_ http.ResponseWriter, r *http.Request) {
-	ctx, span := telemetry.StartSpan(r.Context(), "handle_stream_online")
+func (rt *Router) StreamOnlineHandler(w http.ResponseWriter, r *http.Request) {
+	rt.serveEvent(w, r, rt.handleStreamOnline)
+}
+
+// handleStreamOnline processes a stream.online notification
+func (rt *Router) handleStreamOnline(ctx context.Context, channel config.Channel, _ []byte) error {
+	ctx, span := telemetry.StartSpan(ctx, "handle_stream_online")
 	defer span.End()
 
-	rt.Log.Info("Received stream online event")
+	rt.Log.Info("Received stream online event for channel " + channel.ID)
 
-	rt.streamStartTime = time.Now()
+	startTime := time.Now()
+	rt.Actions.StreamOnline(channel.ID, startTime)
 	telemetry.AddSpanAttributes(span,
 		attribute.String("stream.event", "online"),
-		attribute.String("stream.start_time", rt.streamStartTime.Format(time.RFC3339)),
+		attribute.String("stream.channel_id", channel.ID),
+		attribute.String("stream.start_time", startTime.Format(time.RFC3339)),
 	)
 
-	rt.Log.Info(fmt.Sprintf("Stream started at: %s", rt.streamStartTime.Format(time.RFC3339)))
+	rt.Log.Info(fmt.Sprintf("Stream started at: %s", startTime.Format(time.RFC3339)))
 
-	err := rt.Notification.SendNotification("En vivo y en directo @everyone - https://links.mvaldes.dev/stream")
-	if err != nil {
-		rt.Log.Error("Failed to send stream online notification to discord", err)
-		telemetry.RecordError(span, err)
-	} else {
-		rt.Log.Info("Successfully sent stream online notification to discord")
+	// A configured stream_live_message wins over the catalog template
+	message := rt.Config.StreamLiveMessage(channel)
+	if message == "" {
+		message = rt.Actions.Translate(channel.ID, locale.StreamLive, "{channel}", channel.Name)
+	}
+	if message != "" {
+		if err := rt.Notification.SendNotification(message); err != nil {
+			rt.Log.Error("Failed to send stream online notification to discord", err)
+			telemetry.RecordError(span, err)
+		} else {
+			rt.Log.Info("Successfully sent stream online notification to discord")
+		}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://automate.mvaldes.dev/webhook/stream-live", http.NoBody)
+	// Announcements are best effort, a retry of the event would announce the stream twice
+	webhookURL := rt.Config.Notifications.StreamLiveWebhook
+	if webhookURL == "" {
+		rt.Log.Info("Successfully processed stream online event")
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, http.NoBody)
 	if err != nil {
 		rt.Log.Error("Could not generate request for X post", err)
 		telemetry.RecordError(span, err)
-		return
+		return nil
 	}
 
 	// Validate ADMIN_TOKEN exists before attempting to use it
@@ -608,7 +995,7 @@ func (rt *Router) StreamOnlineHandler(_ http.ResponseWriter, r *http.Request) {
 		errMsg := fmt.Errorf("ADMIN_TOKEN not found in environment - required for webhook notifications. Pass ADMIN_TOKEN as an environment variable at startup")
 		rt.Log.Error("Cannot send stream notification - ADMIN_TOKEN missing from environment", errMsg)
 		telemetry.RecordError(span, errMsg)
-		return
+		return nil
 	}
 
 	req.Header.Add("Token", adminTokenValue)
@@ -617,7 +1004,7 @@ func (rt *Router) StreamOnlineHandler(_ http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		rt.Log.Error("Could not send request to webhook for X post", err)
 		telemetry.RecordError(span, err)
-		return
+		return nil
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode == http.StatusOK {
@@ -627,29 +1014,53 @@ func (rt *Router) StreamOnlineHandler(_ http.ResponseWriter, r *http.Request) {
 	}
 
 	rt.Log.Info("Successfully processed stream online event")
+	return nil
 }
 
 // StreamOfflineHandler tracks when streams end
-func (rt *Router) StreamOfflineHandler(_ http.ResponseWriter, r *http.Request) {
-	ctx, span := telemetry.StartSpan(r.Context(), "handle_stream_offline")
+func (rt *Router) StreamOfflineHandler(w http.ResponseWriter, r *http.Request) {
+	rt.serveEvent(w, r, rt.handleStreamOffline)
+}
+
+// handleStreamOffline processes a stream.offline notification
+func (rt *Router) handleStreamOffline(ctx context.Context, channel config.Channel, _ []byte) error {
+	ctx, span := telemetry.StartSpan(ctx, "handle_stream_offline")
 	defer span.End()
 
-	rt.Log.Info("Received stream offline event")
+	rt.Log.Info("Received stream offline event for channel " + channel.ID)
 
-	if !rt.streamStartTime.IsZero() {
-		duration := time.Since(rt.streamStartTime).Seconds()
+	startTime, live := rt.Actions.StreamOffline(channel.ID)
+
+	if live {
+		duration := time.Since(startTime).Seconds()
 		telemetry.RecordStreamDuration(ctx, duration)
 		telemetry.AddSpanAttributes(span,
 			attribute.String("stream.event", "offline"),
+			attribute.String("stream.channel_id", channel.ID),
 			attribute.Float64("stream.duration_seconds", duration),
 		)
 		rt.Log.Info(fmt.Sprintf("Stream ended, duration: %.2f seconds", duration))
-		rt.streamStartTime = time.Time{} // Reset
 	} else {
 		rt.Log.Info("Stream offline event received but no start time was recorded")
 	}
 
+	// The end-of-stream summary lists the clips made with !clip and the Clip It reward
+	summary, err := rt.Actions.StreamSummary(channel)
+	if err != nil {
+		rt.Log.Error("Failed to load stream clips for the summary", err)
+		telemetry.RecordError(span, err)
+	}
+	if summary != "" {
+		if err := rt.Notification.SendNotification(summary); err != nil {
+			rt.Log.Error("Failed to send stream summary to discord", err)
+			telemetry.RecordError(span, err)
+		} else {
+			rt.Log.Info("Successfully sent stream summary to discord")
+		}
+	}
+
 	rt.Log.Info("Successfully processed stream offline event")
+	return nil
 }
 
 // PlayingHandler displays music playing in spotify
@@ -0,0 +1,196 @@
+package routes
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/actions"
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
+	"github.com/mvaldes14/twitch-bot/pkgs/telemetry"
+)
+
+const testSecret = "s3cr3t-webhook-key"
+
+// sign computes the Twitch-Eventsub-Message-Signature for a delivery
+func sign(secret, messageID, timestamp, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(messageID + timestamp + body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	const (
+		messageID = "e76c6bd4-55c9-4987-8304-da1588d8988b"
+		body      = `{"subscription":{"type":"channel.follow"},"event":{"user_name":"viewer"}}`
+	)
+	timestamp := time.Now().UTC().Format(time.RFC3339Nano)
+	valid := sign(testSecret, messageID, timestamp, body)
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		body       string
+		wantStatus int
+		wantCalled bool
+	}{
+		{
+			name:       "valid signature",
+			headers:    map[string]string{eventSubMessageID: messageID, eventSubMessageTimestamp: timestamp, eventSubMessageSignature: valid},
+			body:       body,
+			wantStatus: http.StatusOK,
+			wantCalled: true,
+		},
+		{
+			name:       "tampered body",
+			headers:    map[string]string{eventSubMessageID: messageID, eventSubMessageTimestamp: timestamp, eventSubMessageSignature: valid},
+			body:       strings.Replace(body, "viewer", "attacker", 1),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "tampered message id",
+			headers:    map[string]string{eventSubMessageID: "other-id", eventSubMessageTimestamp: timestamp, eventSubMessageSignature: valid},
+			body:       body,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "signed with another secret",
+			headers:    map[string]string{eventSubMessageID: messageID, eventSubMessageTimestamp: timestamp, eventSubMessageSignature: sign("wrong", messageID, timestamp, body)},
+			body:       body,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "signature without prefix",
+			headers:    map[string]string{eventSubMessageID: messageID, eventSubMessageTimestamp: timestamp, eventSubMessageSignature: strings.TrimPrefix(valid, "sha256=")},
+			body:       body,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "missing signature",
+			headers:    map[string]string{eventSubMessageID: messageID, eventSubMessageTimestamp: timestamp},
+			body:       body,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "missing timestamp",
+			headers:    map[string]string{eventSubMessageID: messageID, eventSubMessageSignature: valid},
+			body:       body,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "body too large",
+			headers:    map[string]string{eventSubMessageID: messageID, eventSubMessageTimestamp: timestamp, eventSubMessageSignature: valid},
+			body:       strings.Repeat("x", maxEventSubBodyBytes+1),
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	rt := &Router{
+		Log:    telemetry.NewLogger("routes-test"),
+		Config: &config.Config{EventSub: config.EventSub{Secret: testSecret}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				got, err := io.ReadAll(r.Body)
+				if err != nil || string(got) != tt.body {
+					t.Errorf("handler body = %q, %v, want the original body", got, err)
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/follow", strings.NewReader(tt.body))
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			rt.VerifySignature(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("handler called = %v, want %v", called, tt.wantCalled)
+			}
+		})
+	}
+}
+
+func TestServeEventAsksForRetryOnFailure(t *testing.T) {
+	rt := &Router{
+		Log:    telemetry.NewLogger("routes-test"),
+		Config: &config.Config{Broadcaster: config.Channel{ID: "100"}},
+	}
+	body := func(channelID string) string {
+		return `{"subscription":{"type":"channel.follow","condition":{"broadcaster_user_id":"` + channelID + `"}},"event":{}}`
+	}
+	tests := []struct {
+		name       string
+		body       string
+		handlerErr error
+		wantStatus int
+		wantCalled bool
+	}{
+		{name: "handled", body: body("100"), wantStatus: http.StatusOK, wantCalled: true},
+		{name: "handler failed for good", body: body("100"), handlerErr: errorUnknownMessageType, wantStatus: http.StatusNoContent, wantCalled: true},
+		{name: "handler failed for now", body: body("100"), handlerErr: transient(actions.ErrQueueFull), wantStatus: http.StatusInternalServerError, wantCalled: true},
+		{name: "unknown channel is dropped", body: body("999"), wantStatus: http.StatusOK},
+		{name: "malformed envelope", body: "{", wantStatus: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handle := func(_ context.Context, channel config.Channel, _ []byte) error {
+				called = true
+				if channel.ID != "100" {
+					t.Errorf("handler got channel %q", channel.ID)
+				}
+				return tt.handlerErr
+			}
+			rec := httptest.NewRecorder()
+			rt.serveEvent(rec, httptest.NewRequest(http.MethodPost, "/follow", strings.NewReader(tt.body)), handle)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("handler called = %v, want %v", called, tt.wantCalled)
+			}
+		})
+	}
+}
+
+func TestTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "queue full", err: fmt.Errorf("reply: %w", actions.ErrQueueFull), want: true},
+		{name: "network error", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: true},
+		{name: "helix unavailable", err: &actions.HelixError{Status: http.StatusServiceUnavailable}, want: true},
+		{name: "helix rate limited", err: &actions.HelixError{Status: http.StatusTooManyRequests}, want: true},
+		{name: "helix rejected", err: &actions.HelixError{Status: http.StatusNotFound}},
+		{name: "unmarshal error", err: json.Unmarshal([]byte("{"), &struct{}{})},
+		{name: "no error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(transient(tt.err)); got != tt.want {
+				t.Errorf("retryable(transient(%v)) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
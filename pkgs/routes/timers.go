@@ -0,0 +1,111 @@
+// Package routes handles the routes of the server
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/actions"
+	"github.com/mvaldes14/twitch-bot/pkgs/telemetry"
+)
+
+// TimerRequest is the body for creating or updating a timer
+type TimerRequest struct {
+	// Channel is the broadcaster ID owning the timer, defaults to the primary channel
+	Channel string `json:"channel"`
+	actions.Timer
+}
+
+// timerList is the response of ListTimersHandler
+type timerList struct {
+	Channel string          `json:"channel"`
+	Total   int             `json:"total"`
+	Timers  []actions.Timer `json:"timers"`
+}
+
+// ListTimersHandler lists the configured and runtime timers of the ?channel= broadcaster ID
+func (rt *Router) ListTimersHandler(w http.ResponseWriter, r *http.Request) {
+	channelID, err := rt.requestChannel(r.URL.Query().Get("channel"))
+	if err != nil {
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	timers, err := rt.Actions.ListTimers(channelID)
+	if err != nil {
+		rt.Log.Error("Could not list timers", err)
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	if timers == nil {
+		timers = []actions.Timer{}
+	}
+	writeJSON(w, http.StatusOK, timerList{
+		Channel: channelID,
+		Total:   len(timers),
+		Timers:  timers,
+	})
+}
+
+// CreateTimerHandler adds a runtime timer
+func (rt *Router) CreateTimerHandler(w http.ResponseWriter, r *http.Request) {
+	_, span := telemetry.StartSpan(r.Context(), "create_timer")
+	defer span.End()
+
+	var req TimerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		telemetry.RecordError(span, err)
+		writeJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	rt.saveTimer(w, req, http.StatusCreated, rt.Actions.CreateTimer)
+}
+
+// UpdateTimerHandler replaces the runtime timer named by the {name} path value
+func (rt *Router) UpdateTimerHandler(w http.ResponseWriter, r *http.Request) {
+	_, span := telemetry.StartSpan(r.Context(), "update_timer")
+	defer span.End()
+
+	var req TimerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		telemetry.RecordError(span, err)
+		writeJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	req.Name = r.PathValue("name")
+	rt.saveTimer(w, req, http.StatusOK, rt.Actions.UpdateTimer)
+}
+
+// saveTimer stores a timer with the given create or update action
+func (rt *Router) saveTimer(w http.ResponseWriter, req TimerRequest, status int, save func(string, actions.Timer) (actions.Timer, error)) {
+	channelID, err := rt.requestChannel(req.Channel)
+	if err != nil {
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	timer, err := save(channelID, req.Timer)
+	if err != nil {
+		rt.Log.Error("Could not save timer "+req.Name, err)
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	rt.Log.Info(fmt.Sprintf("[TIMER: SAVED] %s for channel %s", timer.Name, channelID))
+	writeJSON(w, status, timer)
+}
+
+// DeleteTimerHandler removes the runtime timer named by the {name} path value
+func (rt *Router) DeleteTimerHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	channelID, err := rt.requestChannel(r.URL.Query().Get("channel"))
+	if err != nil {
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	if err := rt.Actions.DeleteTimer(channelID, name); err != nil {
+		rt.Log.Error("Could not delete timer "+name, err)
+		writeJSON(w, errorStatus(err), err)
+		return
+	}
+	rt.Log.Info(fmt.Sprintf("[TIMER: DELETED] %s for channel %s", name, channelID))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -4,19 +4,24 @@ package routes
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"strings"
 
+	"github.com/mvaldes14/twitch-bot/pkgs/actions"
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
 	"github.com/mvaldes14/twitch-bot/pkgs/subscriptions"
 )
 
 const (
-	userID      = "1792311"
-	callbackURL = "https://bots.mvaldes.dev"
-	secret      = "superSecret123"
-	url         = "https://api.twitch.tv/helix/eventsub/subscriptions"
+	url = "https://api.twitch.tv/helix/eventsub/subscriptions"
 )
 
 // MakeRequestMarshallJSON receives a request and marshals the response into a struct
@@ -46,57 +51,146 @@ func (rt *Router) MakeRequestMarshallJSON(r *RequestJSON, jsonType any) error {
 	return json.Unmarshal(body, jsonType)
 }
 
-// GeneratePayload Builds the payload for each subscription type
-func (rt *Router) GeneratePayload(subType subscriptions.SubscriptionType) string {
+// validSignature checks the Twitch-Eventsub-Message-Signature header, which is
+// "sha256=" followed by the HMAC-SHA256 of message id + timestamp + raw body
+func validSignature(key, messageID, timestamp string, body []byte, signature string) bool {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(messageID))
+	mac.Write([]byte(timestamp))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// firstDelivery records an EventSub message ID and reports whether it had not been seen before.
+// If Redis is unavailable the message is processed rather than dropped.
+func (rt *Router) firstDelivery(messageID string) bool {
+	created, err := rt.Cache.SetIfAbsent("eventsub:message:"+messageID, eventSubReplayWindow)
+	if err != nil {
+		rt.Log.Error("Could not check EventSub message for duplicates, processing anyway", err)
+		return true
+	}
+	return created
+}
+
+// retryableError marks an event that failed for a passing reason, Twitch is asked to deliver it again
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// transient marks err as retryable when a later delivery can succeed: a full chat queue, a network error or a
+// Helix 429 or 5xx. Anything else, such as a malformed body or a rejected request, is returned unchanged
+func transient(err error) error {
+	var (
+		netErr   net.Error
+		helixErr *actions.HelixError
+	)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, actions.ErrQueueFull), errors.As(err, &netErr):
+	case errors.As(err, &helixErr) && (helixErr.Status == http.StatusTooManyRequests || helixErr.Status >= http.StatusInternalServerError):
+	default:
+		return err
+	}
+	return &retryableError{err: err}
+}
+
+// retryable reports whether a failed event should be delivered again
+func retryable(err error) bool {
+	var retry *retryableError
+	return errors.As(err, &retry)
+}
+
+// releaseDelivery forgets an EventSub message ID whose handler failed, so a redelivery is not taken for a duplicate
+func (rt *Router) releaseDelivery(messageID string) {
+	if err := rt.Cache.Delete("eventsub:message:" + messageID); err != nil {
+		rt.Log.Error("Could not release EventSub message "+messageID+", a retry will be skipped", err)
+	}
+}
+
+// callbackFor returns the webhook URL Twitch should deliver a subscription type to.
+// Paths are keyed by EventSub type because both stream subscriptions share the "stream" name.
+func (rt *Router) callbackFor(subType subscriptions.SubscriptionType) string {
+	endpointPath := map[string]string{
+		"channel.subscribe":    "sub",
+		"channel.chat.message": "chat",
+		"channel.follow":       "follow",
+		"channel.cheer":        "cheer",
+		"channel.channel_points_custom_reward_redemption.add": "reward",
+		"stream.online":            "stream-online",
+		"stream.offline":           "stream-offline",
+		actions.PollBegin:          "poll",
+		actions.PollProgress:       "poll",
+		actions.PollEnd:            "poll",
+		actions.PredictionBegin:    "prediction",
+		actions.PredictionProgress: "prediction",
+		actions.PredictionLock:     "prediction",
+		actions.PredictionEnd:      "prediction",
+	}[subType.Type]
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(rt.Config.EventSub.CallbackURL, "/"), endpointPath)
+}
+
+// subscriptionTypeFor finds the known subscription definition for an EventSub type such as "channel.follow"
+func subscriptionTypeFor(eventType string) (subscriptions.SubscriptionType, bool) {
+	for _, subType := range subscriptionTypes {
+		if subType.Type == eventType {
+			return subType, true
+		}
+	}
+	return subscriptions.SubscriptionType{}, false
+}
+
+// GeneratePayload Builds the payload for each subscription type on the given channel
+func (rt *Router) GeneratePayload(broadcasterID string, subType subscriptions.SubscriptionType) string {
 	rt.Log.Info("Generating payload for subscription type")
 
 	// Define the condition based on subscription type
 	condition := map[string]string{
-		"broadcaster_user_id": userID,
+		"broadcaster_user_id": broadcasterID,
 	}
 
 	switch subType.Name {
 	case "chat":
-		condition["user_id"] = userID
+		condition["user_id"] = broadcasterID
 	case "follow":
-		condition["moderator_user_id"] = userID
-	case "subscribe", "cheer", "reward", "stream":
+		condition["moderator_user_id"] = broadcasterID
+	case "subscribe", "cheer", "reward", "stream", "poll", "prediction":
 	}
 
-	// Map subscription names to their endpoint paths
-	endpointPath := map[string]string{
-		"subscribe": "sub",
-		"chat":      "chat",
-		"follow":    "follow",
-		"cheer":     "cheer",
-		"reward":    "reward",
-		"streamon":  "stream-online",
-		"streamoff": "stream-offline",
-	}[subType.Name]
-
 	// Create a struct for the payload
 	payloadStruct := struct {
 		Type      string            `json:"type"`
 		Version   string            `json:"version"`
 		Condition map[string]string `json:"condition"`
 		Transport struct {
-			Method   string `json:"method"`
-			Callback string `json:"callback"`
-			Secret   string `json:"secret"`
+			Method    string `json:"method"`
+			Callback  string `json:"callback,omitempty"`
+			Secret    string `json:"secret,omitempty"`
+			SessionID string `json:"session_id,omitempty"`
 		} `json:"transport"`
 	}{
 		Type:      subType.Type,
 		Version:   subType.Version,
 		Condition: condition,
-		Transport: struct {
-			Method   string `json:"method"`
-			Callback string `json:"callback"`
-			Secret   string `json:"secret"`
-		}{
-			Method:   "webhook",
-			Callback: fmt.Sprintf("%s/%s", callbackURL, endpointPath),
-			Secret:   secret,
-		},
+	}
+
+	// Websocket subscriptions are bound to the session instead of a callback URL
+	if rt.Subs.Transport == config.TransportWebSocket {
+		payloadStruct.Transport.Method = config.TransportWebSocket
+		payloadStruct.Transport.SessionID = rt.currentSession()
+	} else {
+		payloadStruct.Transport.Method = config.TransportWebhook
+		payloadStruct.Transport.Callback = rt.callbackFor(subType)
+		payloadStruct.Transport.Secret = rt.Config.EventSub.Secret
 	}
 
 	// Marshal the entire payload
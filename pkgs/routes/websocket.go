@@ -0,0 +1,111 @@
+// Package routes defines all routes for handlers and functionality
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mvaldes14/twitch-bot/pkgs/actions"
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
+	"github.com/mvaldes14/twitch-bot/pkgs/subscriptions"
+	"github.com/mvaldes14/twitch-bot/pkgs/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// eventHandlers maps EventSub types to the handler that processes their notifications.
+// Webhook routes and the websocket client both end up in these handlers.
+func (rt *Router) eventHandlers() map[string]eventHandler {
+	return map[string]eventHandler{
+		"channel.chat.message": rt.handleChat,
+		"channel.follow":       rt.handleFollow,
+		"channel.subscribe":    rt.handleSub,
+		"channel.cheer":        rt.handleCheer,
+		"channel.channel_points_custom_reward_redemption.add": rt.handleReward,
+		"stream.online":            rt.handleStreamOnline,
+		"stream.offline":           rt.handleStreamOffline,
+		actions.PollBegin:          rt.handlePoll,
+		actions.PollProgress:       rt.handlePoll,
+		actions.PollEnd:            rt.handlePoll,
+		actions.PredictionBegin:    rt.handlePrediction,
+		actions.PredictionProgress: rt.handlePrediction,
+		actions.PredictionLock:     rt.handlePrediction,
+		actions.PredictionEnd:      rt.handlePrediction,
+	}
+}
+
+// HandleSession stores the new websocket session and reconciles so every
+// subscription is created against it
+func (rt *Router) HandleSession(ctx context.Context, sessionID string) {
+	rt.sessionMu.Lock()
+	rt.sessionID = sessionID
+	rt.sessionMu.Unlock()
+
+	if _, err := rt.Reconcile(ctx); err != nil {
+		rt.Log.Error("Failed to create subscriptions for websocket session "+sessionID, err)
+	}
+}
+
+// HandleNotification dispatches a websocket notification to the same handler its webhook route uses
+func (rt *Router) HandleNotification(ctx context.Context, messageID, subscriptionType string, payload []byte) {
+	ctx, span := telemetry.StartSpan(ctx, "eventsub_websocket_notification",
+		attribute.String("eventsub.message_id", messageID),
+		attribute.String("eventsub.subscription_type", subscriptionType),
+	)
+	defer span.End()
+
+	if !rt.firstDelivery(messageID) {
+		rt.Log.Info(fmt.Sprintf("[EVENTSUB: DUPLICATE] Skipping already processed message %s", messageID))
+		telemetry.IncrementEventSubRejected(ctx, "duplicate")
+		return
+	}
+
+	handle, ok := rt.eventHandlers()[subscriptionType]
+	if !ok {
+		rt.Log.Info("No handler for websocket notification of type " + subscriptionType)
+		return
+	}
+	if err := rt.dispatch(ctx, payload, handle); err != nil {
+		telemetry.RecordError(span, err)
+		if retryable(err) {
+			rt.releaseDelivery(messageID)
+		}
+	}
+}
+
+// HandleRevocation processes a revocation received over the websocket
+func (rt *Router) HandleRevocation(ctx context.Context, messageID string, payload []byte) {
+	if !rt.firstDelivery(messageID) {
+		return
+	}
+	var revocation subscriptions.EventSubMessage
+	if err := json.Unmarshal(payload, &revocation); err != nil {
+		rt.Log.Error("Failed to unmarshal websocket revocation payload", err)
+		return
+	}
+	rt.handleRevocation(ctx, revocation.Subscription)
+}
+
+// currentSession returns the active websocket session ID, empty when not connected
+func (rt *Router) currentSession() string {
+	rt.sessionMu.RLock()
+	defer rt.sessionMu.RUnlock()
+	return rt.sessionID
+}
+
+// transportTarget is where subscriptions of a type should deliver: the callback URL
+// for webhooks or the current session ID for websockets
+func (rt *Router) transportTarget(subType subscriptions.SubscriptionType) string {
+	if rt.Subs.Transport == config.TransportWebSocket {
+		return rt.currentSession()
+	}
+	return rt.callbackFor(subType)
+}
+
+// subscriptionTarget is where an existing subscription currently delivers
+func subscriptionTarget(sub subscriptions.SubscriptionData) string {
+	if sub.Transport.Method == config.TransportWebSocket {
+		return sub.Transport.SessionID
+	}
+	return sub.Transport.Callback
+}
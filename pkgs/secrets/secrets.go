@@ -16,8 +16,10 @@ import (
 	"time"
 
 	"github.com/mvaldes14/twitch-bot/pkgs/cache"
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
 	"github.com/mvaldes14/twitch-bot/pkgs/telemetry"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -46,6 +48,7 @@ var (
 	errInvalidRequest   = errors.New("failed to create HTTP request")
 	errHTTPRequest      = errors.New("HTTP request failed")
 	errResponseParsing  = errors.New("failed to parse response")
+	errUnknownChannel   = errors.New("channel or bot account is not configured")
 )
 
 // SecretService implements SecretManager interface
@@ -53,14 +56,65 @@ type SecretService struct {
 	Log        *telemetry.CustomLogger
 	Cache      *cache.Service
 	httpClient *http.Client
+	// Channels hold a user token each, stored under a per-channel cache key
+	Channels []config.Channel
+	// Bot is the optional account chat messages are sent as, its user token is cached like a channel's
+	Bot config.Bot
 }
 
-// NewSecretService creates a new instance of SecretService
-func NewSecretService() *SecretService {
+// account is a Twitch user the service keeps a user token for, a channel or the bot
+type account struct {
+	ID              string
+	RefreshTokenEnv string
+	UserTokenEnv    string
+}
+
+// NewSecretService creates a new instance of SecretService for every configured channel
+func NewSecretService(cfg *config.Config) *SecretService {
 	logger := telemetry.NewLogger("secrets")
 	cacheService := cache.NewCacheService()
 	httpClient := &http.Client{Timeout: requestTimeout}
-	return &SecretService{Log: logger, Cache: cacheService, httpClient: httpClient}
+	return &SecretService{Log: logger, Cache: cacheService, httpClient: httpClient, Channels: cfg.AllChannels(), Bot: cfg.Bot}
+}
+
+// userTokenKey is the cache key of a channel's user token
+func userTokenKey(channelID string) string {
+	return twitchUserToken + ":" + channelID
+}
+
+// refreshTokenKey is the cache key of a channel's latest refresh token
+func refreshTokenKey(channelID string) string {
+	return twitchRefreshToken + ":" + channelID
+}
+
+// accounts lists every user the service keeps a token for: the channels, then the bot when configured
+func (s *SecretService) accounts() []account {
+	accounts := make([]account, 0, len(s.Channels)+1)
+	for _, channel := range s.Channels {
+		accounts = append(accounts, account{ID: channel.ID, RefreshTokenEnv: channel.RefreshTokenEnv, UserTokenEnv: channel.UserTokenEnv})
+	}
+	if s.Bot.Enabled() {
+		accounts = append(accounts, account{ID: s.Bot.ID, RefreshTokenEnv: s.Bot.RefreshTokenEnv, UserTokenEnv: s.Bot.UserTokenEnv})
+	}
+	return accounts
+}
+
+// account finds a channel or the bot so its token environment variables are known
+func (s *SecretService) account(userID string) (account, error) {
+	for _, acc := range s.accounts() {
+		if acc.ID == userID {
+			return acc, nil
+		}
+	}
+	return account{}, fmt.Errorf("%w: %s", errUnknownChannel, userID)
+}
+
+// SenderID is the user chat messages in a channel are sent as: the bot account when configured, otherwise the broadcaster
+func (s *SecretService) SenderID(channelID string) string {
+	if s.Bot.Enabled() {
+		return s.Bot.ID
+	}
+	return channelID
 }
 
 // GetEnvironmentVariable retrieves an environment variable and validates it exists and is not empty.
@@ -78,42 +132,20 @@ func (s *SecretService) GetEnvironmentVariable(key string) (string, error) {
 
 // InitSecrets initializes the secrets by loading tokens.
 // App tokens (TWITCH_APP_TOKEN) are auto-generated via client credentials.
-// User tokens (TWITCH_USER_TOKEN) are generated from TWITCH_REFRESH_TOKEN and expire every 4 hours. They are automatically refreshed by the background goroutine before expiry.
+// User tokens (TWITCH_USER_TOKEN:<user id>) are generated from the refresh token of each channel and of the bot account, and expire every 4 hours. They are automatically refreshed by the background goroutine before expiry.
 func (s *SecretService) InitSecrets() {
 	ctx := context.Background()
 	_, span := telemetry.StartSpan(ctx, "secrets.init_secrets")
 	defer span.End()
 
-	// Twitch User Token - generated from refresh token, expires every 4 hours
-	if _, err := s.Cache.GetToken("TWITCH_USER_TOKEN"); err != nil {
-		// Try to load from environment variable first (for initial startup)
-		userTokenFromEnv := os.Getenv(twitchUserToken)
-		if userTokenFromEnv != "" {
-			// Store the environment variable value in Redis with 4-hour TTL
-			s.Log.Info("[SOURCE: ENV VAR] TWITCH_USER_TOKEN loaded from environment variable")
-			if err := s.Cache.StoreToken(cache.Token{
-				Key:        "TWITCH_USER_TOKEN",
-				Value:      userTokenFromEnv,
-				Expiration: time.Duration(twitchUserExpiration) * time.Second,
-			}); err != nil {
-				s.Log.Error("Failed to store TWITCH_USER_TOKEN in Redis:", err)
-			}
-		} else {
-			// No env var, try to generate from refresh token
-			s.Log.Info("[SOURCE: GENERATED] TWITCH_USER_TOKEN not in environment, generating from refresh token")
-			newToken, expiresIn, err := s.RefreshUserToken()
-			if err != nil {
-				s.Log.Error("Failed to generate TWITCH_USER_TOKEN from refresh token - initial token may not have been provided:", err)
-			} else {
-				if err := s.Cache.StoreToken(cache.Token{
-					Key:        "TWITCH_USER_TOKEN",
-					Value:      newToken,
-					Expiration: time.Duration(expiresIn) * time.Second,
-				}); err != nil {
-					s.Log.Error("Failed to store TWITCH_USER_TOKEN in Redis:", err)
-				}
-			}
-		}
+	// Tokens cached before channels had their own keys belong to the primary channel
+	if len(s.Channels) > 0 {
+		s.migrateLegacyTokens(s.Channels[0].ID)
+	}
+
+	// Twitch User Tokens - one per channel and the bot, generated from refresh tokens, expire every 4 hours
+	for _, acc := range s.accounts() {
+		s.initUserToken(acc)
 	}
 
 	// Twitch App Token - always generate fresh via client credentials on startup
@@ -149,6 +181,61 @@ func (s *SecretService) InitSecrets() {
 	}
 }
 
+// migrateLegacyTokens copies the user and refresh tokens cached under the keys without a channel suffix to the
+// primary channel's keys, so a rotated refresh token is not lost when upgrading from a single channel deployment.
+// The legacy keys are left in place for a rollback.
+func (s *SecretService) migrateLegacyTokens(channelID string) {
+	legacy := []struct {
+		from       string
+		to         string
+		expiration time.Duration
+	}{
+		{from: twitchRefreshToken, to: refreshTokenKey(channelID), expiration: 365 * 24 * time.Hour},
+		{from: twitchUserToken, to: userTokenKey(channelID), expiration: time.Duration(twitchUserExpiration) * time.Second},
+	}
+	for _, key := range legacy {
+		if _, err := s.Cache.GetToken(key.to); err == nil {
+			continue
+		}
+		value, err := s.Cache.GetToken(key.from)
+		if err != nil || value == "" {
+			continue
+		}
+		s.Log.Info(fmt.Sprintf("[SOURCE: MIGRATED] [CHANNEL: %s] %s copied to %s", channelID, key.from, key.to))
+		if err := s.Cache.StoreToken(cache.Token{Key: key.to, Value: value, Expiration: key.expiration}); err != nil {
+			s.Log.Error("Failed to migrate "+key.from+" to "+key.to, err)
+		}
+	}
+}
+
+// initUserToken stores an account's user token from its environment variable, or generates one from its refresh token
+func (s *SecretService) initUserToken(acc account) {
+	key := userTokenKey(acc.ID)
+	if _, err := s.Cache.GetToken(key); err == nil {
+		return
+	}
+
+	// Try to load from environment variable first (for initial startup)
+	if userTokenFromEnv := os.Getenv(acc.UserTokenEnv); acc.UserTokenEnv != "" && userTokenFromEnv != "" {
+		// Store the environment variable value in Redis with 4-hour TTL
+		s.Log.Info(fmt.Sprintf("[SOURCE: ENV VAR] [CHANNEL: %s] User token loaded from %s", acc.ID, acc.UserTokenEnv))
+		if err := s.Cache.StoreToken(cache.Token{
+			Key:        key,
+			Value:      userTokenFromEnv,
+			Expiration: time.Duration(twitchUserExpiration) * time.Second,
+		}); err != nil {
+			s.Log.Error("Failed to store "+key+" in Redis:", err)
+		}
+		return
+	}
+
+	// No env var, try to generate from refresh token
+	s.Log.Info(fmt.Sprintf("[SOURCE: GENERATED] [CHANNEL: %s] User token not in environment, generating from refresh token", acc.ID))
+	if err := s.refreshAndStoreUserToken(acc.ID); err != nil {
+		s.Log.Error("Failed to generate "+key+" from refresh token - initial token may not have been provided:", err)
+	}
+}
+
 // BuildSecretHeaders reads the app token from Redis cache and returns headers for Twitch API requests.
 // Validates that the token exists in cache and that the client ID environment variable is set before returning, fails early if missing.
 func (s *SecretService) BuildSecretHeaders() (RequestHeader, error) {
@@ -180,17 +267,19 @@ func (s *SecretService) BuildSecretHeaders() (RequestHeader, error) {
 	}, nil
 }
 
-// GetUserToken reads the user token from Redis cache.
+// GetUserToken reads a channel's user token from Redis cache.
 // Returns a specific error if the token is missing, which would prevent user-scoped Twitch API operations.
-func (s *SecretService) GetUserToken() (string, error) {
+func (s *SecretService) GetUserToken(channelID string) (string, error) {
 	ctx := context.Background()
-	_, span := telemetry.StartSpan(ctx, "secrets.get_user_token")
+	_, span := telemetry.StartSpan(ctx, "secrets.get_user_token",
+		attribute.String("twitch.channel_id", channelID),
+	)
 	defer span.End()
 
-	token, err := s.Cache.GetToken(twitchUserToken)
+	token, err := s.Cache.GetToken(userTokenKey(channelID))
 	if err != nil || token == "" {
-		tokenErr := fmt.Errorf("TWITCH_USER_TOKEN not found in Redis cache - required for Twitch API user-scoped operations. Token expires every 4 hours. If missing, the background renewal goroutine will automatically generate a new one. If still missing after refresh, the TWITCH_REFRESH_TOKEN may be invalid or revoked: %w", err)
-		s.Log.Error("TWITCH_USER_TOKEN missing from cache", tokenErr)
+		tokenErr := fmt.Errorf("user token for channel "+channelID+" not found in Redis cache - required for Twitch API user-scoped operations. Token expires every 4 hours. If missing, the background renewal goroutine will automatically generate a new one. If still missing after refresh, the channel's refresh token may be invalid or revoked: %w", err)
+		s.Log.Error(userTokenKey(channelID)+" missing from cache", tokenErr)
 		telemetry.RecordError(span, tokenErr)
 		return "", tokenErr
 	}
@@ -247,19 +336,27 @@ func (s *SecretService) RefreshAppToken() (string, int, error) {
 	return response.AccessToken, expiresIn, nil
 }
 
-// RefreshUserToken generates a new user token from the refresh token. User tokens expire every 4 hours and are auto-refreshed by the background goroutine.
-func (s *SecretService) RefreshUserToken() (string, int, error) {
+// RefreshUserToken generates a new user token for a channel or the bot account from its refresh token. User tokens expire every 4 hours and are auto-refreshed by the background goroutine.
+func (s *SecretService) RefreshUserToken(channelID string) (string, int, error) {
 	ctx := context.Background()
 	_, span := telemetry.StartExternalSpan(ctx, "twitch.refresh_user_token", "twitch", "refresh_user_token")
 	defer span.End()
+	telemetry.AddSpanAttributes(span, attribute.String("twitch.channel_id", channelID))
+
+	acc, err := s.account(channelID)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		telemetry.IncrementTokenRefreshTotal(ctx, "user", "error")
+		return "", 0, err
+	}
 
 	twitchID := os.Getenv(twitchClientID)
 	twitchSecretVal := os.Getenv(twitchSecret)
 
-	// Read refresh token from Redis first, fall back to env var
-	twitchRefreshTk, err := s.Cache.GetToken(twitchRefreshToken)
+	// Read refresh token from Redis first, fall back to the account's env var
+	twitchRefreshTk, err := s.Cache.GetToken(refreshTokenKey(channelID))
 	if err != nil || twitchRefreshTk == "" {
-		twitchRefreshTk = os.Getenv(twitchRefreshToken)
+		twitchRefreshTk = os.Getenv(acc.RefreshTokenEnv)
 	}
 
 	if twitchID == "" || twitchSecretVal == "" || twitchRefreshTk == "" {
@@ -300,7 +397,7 @@ func (s *SecretService) RefreshUserToken() (string, int, error) {
 	// Store new refresh token if provided in response
 	if response.RefreshToken != "" {
 		if err := s.Cache.StoreToken(cache.Token{
-			Key:        twitchRefreshToken,
+			Key:        refreshTokenKey(channelID),
 			Value:      response.RefreshToken,
 			Expiration: 365 * 24 * time.Hour, // Long TTL for refresh token
 		}); err != nil {
@@ -478,19 +575,21 @@ func (s *SecretService) refreshAndStoreAppToken() error {
 	return nil
 }
 
-// refreshAndStoreUserToken refreshes the Twitch user token and stores it in Redis.
-func (s *SecretService) refreshAndStoreUserToken() error {
+// refreshAndStoreUserToken refreshes a channel's Twitch user token and stores it in Redis.
+func (s *SecretService) refreshAndStoreUserToken(channelID string) error {
 	ctx := context.Background()
-	_, span := telemetry.StartSpan(ctx, "secrets.refresh_and_store_user_token")
+	_, span := telemetry.StartSpan(ctx, "secrets.refresh_and_store_user_token",
+		attribute.String("twitch.channel_id", channelID),
+	)
 	defer span.End()
 
-	newToken, expiresIn, err := s.RefreshUserToken()
+	newToken, expiresIn, err := s.RefreshUserToken(channelID)
 	if err != nil {
 		telemetry.RecordError(span, err)
 		return fmt.Errorf("failed to refresh user token: %w", err)
 	}
 	if err := s.Cache.StoreToken(cache.Token{
-		Key:        twitchUserToken,
+		Key:        userTokenKey(channelID),
 		Value:      newToken,
 		Expiration: time.Duration(expiresIn) * time.Second,
 	}); err != nil {
@@ -498,7 +597,7 @@ func (s *SecretService) refreshAndStoreUserToken() error {
 		return fmt.Errorf("failed to store user token: %w", err)
 	}
 	telemetry.AddSpanAttributes(span, attribute.Int("token.expires_in", expiresIn))
-	s.Log.Info("Twitch user token refreshed, expires in:", expiresIn)
+	s.Log.Info("Twitch user token refreshed for channel "+channelID+", expires in:", expiresIn)
 	return nil
 }
 
@@ -531,10 +630,10 @@ func (s *SecretService) RefreshAppTokenAndStore() error {
 	return s.refreshAndStoreAppToken()
 }
 
-// RefreshUserTokenAndStore refreshes the Twitch user token and stores it in Redis.
+// RefreshUserTokenAndStore refreshes a channel's Twitch user token and stores it in Redis.
 // Exported for use by other packages on 401 detection.
-func (s *SecretService) RefreshUserTokenAndStore() error {
-	return s.refreshAndStoreUserToken()
+func (s *SecretService) RefreshUserTokenAndStore(channelID string) error {
+	return s.refreshAndStoreUserToken(channelID)
 }
 
 // StartTokenRenewal launches a background goroutine that periodically validates
@@ -590,27 +689,9 @@ func (s *SecretService) renewTokens() {
 		telemetry.IncrementTokenValidationTotal(ctx, "app", true)
 	}
 
-	// Twitch User Token — expires every 4 hours, proactively refresh
-	userToken, err := s.Cache.GetToken(twitchUserToken)
-	switch {
-	case err != nil || userToken == "":
-		s.Log.Info("Twitch user token missing from cache, generating new token")
-		telemetry.AddSpanAttributes(span, attribute.String("user_token.action", "refresh_missing"))
-		if err := s.refreshAndStoreUserToken(); err != nil {
-			s.Log.Error("Background renewal: failed to generate user token", err)
-			telemetry.RecordError(span, err)
-		}
-	case !s.ValidateToken(userToken):
-		s.Log.Info("Twitch user token failed validation, generating new token")
-		telemetry.AddSpanAttributes(span, attribute.String("user_token.action", "refresh_invalid"))
-		telemetry.IncrementTokenValidationTotal(ctx, "user", false)
-		if err := s.refreshAndStoreUserToken(); err != nil {
-			s.Log.Error("Background renewal: failed to generate user token", err)
-			telemetry.RecordError(span, err)
-		}
-	default:
-		telemetry.AddSpanAttributes(span, attribute.String("user_token.action", "still_valid"))
-		telemetry.IncrementTokenValidationTotal(ctx, "user", true)
+	// Twitch User Tokens — one per channel and the bot, expire every 4 hours, proactively refresh
+	for _, acc := range s.accounts() {
+		s.renewUserToken(ctx, span, acc.ID)
 	}
 
 	// Spotify Token — expires every hour
@@ -625,3 +706,29 @@ func (s *SecretService) renewTokens() {
 		telemetry.AddSpanAttributes(span, attribute.String("spotify_token.action", "still_valid"))
 	}
 }
+
+// renewUserToken validates a channel's user token and refreshes it if expired or missing.
+func (s *SecretService) renewUserToken(ctx context.Context, span trace.Span, channelID string) {
+	action := "user_token." + channelID + ".action"
+	userToken, err := s.Cache.GetToken(userTokenKey(channelID))
+	switch {
+	case err != nil || userToken == "":
+		s.Log.Info("Twitch user token missing from cache for channel " + channelID + ", generating new token")
+		telemetry.AddSpanAttributes(span, attribute.String(action, "refresh_missing"))
+		if err := s.refreshAndStoreUserToken(channelID); err != nil {
+			s.Log.Error("Background renewal: failed to generate user token for channel "+channelID, err)
+			telemetry.RecordError(span, err)
+		}
+	case !s.ValidateToken(userToken):
+		s.Log.Info("Twitch user token failed validation for channel " + channelID + ", generating new token")
+		telemetry.AddSpanAttributes(span, attribute.String(action, "refresh_invalid"))
+		telemetry.IncrementTokenValidationTotal(ctx, "user", false)
+		if err := s.refreshAndStoreUserToken(channelID); err != nil {
+			s.Log.Error("Background renewal: failed to generate user token for channel "+channelID, err)
+			telemetry.RecordError(span, err)
+		}
+	default:
+		telemetry.AddSpanAttributes(span, attribute.String(action, "still_valid"))
+		telemetry.IncrementTokenValidationTotal(ctx, "user", true)
+	}
+}
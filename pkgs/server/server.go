@@ -2,43 +2,81 @@
 package server
 
 import (
+	"context"
 	"net/http"
 	"time"
 
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
+	"github.com/mvaldes14/twitch-bot/pkgs/eventsub"
 	"github.com/mvaldes14/twitch-bot/pkgs/routes"
 	"github.com/mvaldes14/twitch-bot/pkgs/secrets"
 	"github.com/mvaldes14/twitch-bot/pkgs/subscriptions"
 )
 
-// NewServer creates the http server
-func NewServer(port string) *http.Server {
-	secretService := secrets.NewSecretService()
-	subs := subscriptions.NewSubscription(secretService)
-	rs := routes.NewRouter(subs, secretService)
+// NewServer creates the http server and starts the subscription reconciler and,
+// when using the websocket transport, the EventSub websocket client. Both stop when ctx is cancelled
+func NewServer(ctx context.Context, port string, cfg *config.Config) *http.Server {
+	secretService := secrets.NewSecretService(cfg)
+	subs := subscriptions.NewSubscription(secretService, cfg)
+	rs := routes.NewRouter(subs, secretService, cfg)
 	api := http.NewServeMux()
 	api.HandleFunc("POST /create", rs.CreateHandler)
 	api.HandleFunc("POST /delete", rs.DeleteHandler)
+	api.HandleFunc("DELETE /subscriptions/{id}", rs.DeleteSubscriptionHandler)
 	api.HandleFunc("GET /list", rs.ListHandler)
 	api.HandleFunc("GET /test", rs.TestHandler)
+	api.HandleFunc("GET /reconcile", rs.ReconcileHandler)
+	api.HandleFunc("POST /reconcile", rs.ReconcileHandler)
+	api.HandleFunc("GET /commands", rs.ListCommandsHandler)
+	api.HandleFunc("GET /commands/{name}", rs.GetCommandHandler)
+	api.HandleFunc("POST /commands", rs.CreateCommandHandler)
+	api.HandleFunc("PUT /commands/{name}", rs.UpdateCommandHandler)
+	api.HandleFunc("DELETE /commands/{name}", rs.DeleteCommandHandler)
+	api.HandleFunc("GET /timers", rs.ListTimersHandler)
+	api.HandleFunc("POST /timers", rs.CreateTimerHandler)
+	api.HandleFunc("PUT /timers/{name}", rs.UpdateTimerHandler)
+	api.HandleFunc("DELETE /timers/{name}", rs.DeleteTimerHandler)
+	api.HandleFunc("GET /presets", rs.ListPresetsHandler)
+	api.HandleFunc("POST /presets", rs.CreatePresetHandler)
+	api.HandleFunc("PUT /presets/{name}", rs.UpdatePresetHandler)
+	api.HandleFunc("DELETE /presets/{name}", rs.DeletePresetHandler)
+	api.HandleFunc("GET /moderation/audit", rs.ModerationAuditHandler)
+
+	// EventSub callbacks must be signed by Twitch and delivered only once before any handler sees them
+	webhook := func(h http.HandlerFunc) http.Handler {
+		return rs.VerifySignature(rs.RejectReplays(rs.MiddleWareRoute(h)))
+	}
 
 	router := http.NewServeMux()
-	router.HandleFunc("/follow", rs.FollowHandler)
-	router.HandleFunc("/chat", rs.ChatHandler)
-	router.HandleFunc("/sub", rs.SubHandler)
-	router.HandleFunc("/cheer", rs.CheerHandler)
-	router.HandleFunc("/reward", rs.RewardHandler)
-	router.HandleFunc("/stream-online", rs.StreamOnlineHandler)
-	router.HandleFunc("/stream-offline", rs.StreamOfflineHandler)
+	router.Handle("/follow", webhook(rs.FollowHandler))
+	router.Handle("/chat", webhook(rs.ChatHandler))
+	router.Handle("/sub", webhook(rs.SubHandler))
+	router.Handle("/cheer", webhook(rs.CheerHandler))
+	router.Handle("/reward", webhook(rs.RewardHandler))
+	router.Handle("/stream-online", webhook(rs.StreamOnlineHandler))
+	router.Handle("/stream-offline", webhook(rs.StreamOfflineHandler))
+	router.Handle("/poll", webhook(rs.PollHandler))
+	router.Handle("/prediction", webhook(rs.PredictionHandler))
 	router.HandleFunc("/health", rs.HealthHandler)
 	router.HandleFunc("/playing", rs.PlayingHandler)
 	router.HandleFunc("/playlist", rs.PlaylistHandler)
+	router.HandleFunc("GET /overlay/poll", rs.OverlayPollHandler)
+	router.HandleFunc("GET /overlay/prediction", rs.OverlayPredictionHandler)
 	router.HandleFunc("/test", rs.TestHandler)
 
 	router.Handle("/api/", http.StripPrefix("/api", rs.CheckAuthAdmin(api)))
 
+	rs.StartReconciler(ctx)
+	rs.Actions.StartMessageQueue(ctx)
+	go rs.Actions.SyncStreams(ctx)
+	rs.Actions.StartTimers(ctx)
+	if subs.Transport == config.TransportWebSocket {
+		go eventsub.NewClient(cfg.EventSub.WebSocketURL, rs).Run(ctx)
+	}
+
 	srv := &http.Server{
 		Addr:              port,
-		Handler:           rs.TracingMiddleware(rs.MiddleWareRoute(router)),
+		Handler:           rs.TracingMiddleware(router),
 		ReadHeaderTimeout: 10 * time.Second,
 	}
 	return srv
@@ -9,8 +9,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 
 	"github.com/mvaldes14/twitch-bot/pkgs/cache"
+	"github.com/mvaldes14/twitch-bot/pkgs/config"
 	"github.com/mvaldes14/twitch-bot/pkgs/secrets"
 	"github.com/mvaldes14/twitch-bot/pkgs/telemetry"
 )
@@ -18,9 +20,15 @@ import (
 const (
 	// URL endpoint for all twitch subscriptions
 	URL = "https://api.twitch.tv/helix/eventsub/subscriptions"
+
+	// maxListPages bounds pagination in case Helix keeps returning a cursor
+	maxListPages = 50
 )
 
 var (
+	// ErrSubscriptionNotFound is returned when Twitch has no subscription with the given ID
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+
 	errFailedSubscriptionDeletion = errors.New("failed to delete subscription")
 	errFailedToFormRequest        = errors.New("failed to form request")
 )
@@ -30,17 +38,38 @@ type Subscription struct {
 	Secrets *secrets.SecretService
 	Log     *telemetry.CustomLogger
 	Cache   *cache.Service
+	// Transport is either config.TransportWebhook or config.TransportWebSocket
+	Transport string
+	// ChannelID owns the user token websocket subscriptions are managed with
+	ChannelID string
 }
 
-// NewSubscription creates a new subscription
-func NewSubscription(secretService *secrets.SecretService) *Subscription {
+// NewSubscription creates a new subscription using the transport from the EventSub config
+func NewSubscription(secretService *secrets.SecretService, cfg *config.Config) *Subscription {
 	log := telemetry.NewLogger("subscriptions")
 	cacheService := cache.NewCacheService()
 	return &Subscription{
-		Secrets: secretService,
-		Log:     log,
-		Cache:   cacheService,
+		Secrets:   secretService,
+		Log:       log,
+		Cache:     cacheService,
+		Transport: cfg.EventSub.Transport,
+		ChannelID: cfg.Broadcaster.ID,
+	}
+}
+
+// requestHeaders returns the credentials for EventSub API calls.
+// Webhook subscriptions are managed with the app token, websocket subscriptions require the user token.
+func (s *Subscription) requestHeaders() (secrets.RequestHeader, error) {
+	headers, err := s.Secrets.BuildSecretHeaders()
+	if err != nil || s.Transport != config.TransportWebSocket {
+		return headers, err
+	}
+	userToken, err := s.Secrets.GetUserToken(s.ChannelID)
+	if err != nil {
+		return secrets.RequestHeader{}, err
 	}
+	headers.Token = userToken
+	return headers, nil
 }
 
 // CreateSubscription Generates a new subscription on an event type
@@ -51,7 +80,7 @@ func (s *Subscription) CreateSubscription(payload string) error {
 	defer span.End()
 
 	// Validate Twitch API credentials before attempting subscription creation
-	headers, err := s.Secrets.BuildSecretHeaders()
+	headers, err := s.requestHeaders()
 	if err != nil {
 		errMsg := fmt.Errorf("cannot create subscription without valid Twitch credentials: %w", err)
 		s.Log.Error("Subscription creation failed - missing required credentials (TWITCH_APP_TOKEN or TWITCH_CLIENT_ID)", errMsg)
@@ -116,14 +145,20 @@ func (s *Subscription) CreateSubscription(payload string) error {
 }
 
 // GetSubscriptions Retrieves all subscriptions for the application
-// Validates headers exist before making API call
 func (s *Subscription) GetSubscriptions() (ValidateSubscription, error) {
+	return s.ListSubscriptions(SubscriptionFilter{})
+}
+
+// ListSubscriptions retrieves every page of subscriptions matching the filter.
+// Helix accepts a single filter per request, so when both status and type are
+// given the status is sent to Helix and the type is applied to the results.
+func (s *Subscription) ListSubscriptions(filter SubscriptionFilter) (ValidateSubscription, error) {
 	ctx := context.Background()
-	_, span := telemetry.StartSpan(ctx, "subscription.get_all")
+	ctx, span := telemetry.StartSpan(ctx, "subscription.get_all")
 	defer span.End()
 
 	// Validate Twitch API credentials before attempting to list subscriptions
-	headers, err := s.Secrets.BuildSecretHeaders()
+	headers, err := s.requestHeaders()
 	if err != nil {
 		errMsg := fmt.Errorf("cannot list subscriptions without valid Twitch credentials: %w", err)
 		s.Log.Error("Cannot list subscriptions - Twitch API credentials (TWITCH_APP_TOKEN) missing from Redis cache or TWITCH_CLIENT_ID missing from environment", errMsg)
@@ -131,7 +166,63 @@ func (s *Subscription) GetSubscriptions() (ValidateSubscription, error) {
 		return ValidateSubscription{}, errMsg
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", URL, nil)
+	query := url.Values{}
+	switch {
+	case filter.Status != "":
+		query.Set("status", filter.Status)
+	case filter.Type != "":
+		query.Set("type", filter.Type)
+	}
+
+	var subscriptionList ValidateSubscription
+	for page := 1; ; page++ {
+		pageList, err := s.listPage(ctx, headers, query)
+		if err != nil {
+			telemetry.RecordError(span, err)
+			return ValidateSubscription{}, err
+		}
+		subscriptionList.Data = append(subscriptionList.Data, pageList.Data...)
+		subscriptionList.Total = pageList.Total
+		subscriptionList.TotalCost = pageList.TotalCost
+		subscriptionList.MaxTotalCost = pageList.MaxTotalCost
+
+		if pageList.Pagination.Cursor == "" || len(pageList.Data) == 0 {
+			break
+		}
+		if page >= maxListPages {
+			s.Log.Info(fmt.Sprintf("Stopping subscription listing after %d pages", maxListPages))
+			break
+		}
+		query.Set("after", pageList.Pagination.Cursor)
+	}
+
+	if filter.Status != "" && filter.Type != "" {
+		filtered := subscriptionList.Data[:0]
+		for _, sub := range subscriptionList.Data {
+			if sub.Type == filter.Type {
+				filtered = append(filtered, sub)
+			}
+		}
+		subscriptionList.Data = filtered
+		subscriptionList.Total = len(filtered)
+	}
+
+	// Log subscription details
+	s.Log.Info(fmt.Sprintf("Retrieved %d subscriptions (Total Cost: %d/%d)", subscriptionList.Total, subscriptionList.TotalCost, subscriptionList.MaxTotalCost))
+	for _, sub := range subscriptionList.Data {
+		s.Log.Info(fmt.Sprintf("  - ID: %s, Type: %s, Status: %s, Version: %s, Cost: %d", sub.ID, sub.Type, sub.Status, sub.Version, sub.Cost))
+	}
+
+	return subscriptionList, nil
+}
+
+// listPage fetches a single page of subscriptions
+func (s *Subscription) listPage(ctx context.Context, headers secrets.RequestHeader, query url.Values) (ValidateSubscription, error) {
+	listURL := URL
+	if len(query) > 0 {
+		listURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", listURL, nil)
 	if err != nil {
 		return ValidateSubscription{}, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -162,65 +253,82 @@ func (s *Subscription) GetSubscriptions() (ValidateSubscription, error) {
 		return ValidateSubscription{}, fmt.Errorf("failed to read response: %w", err)
 	}
 	var subscriptionList ValidateSubscription
-	err = json.Unmarshal(body, &subscriptionList)
-	if err != nil {
+	if err := json.Unmarshal(body, &subscriptionList); err != nil {
 		s.Log.Error("Error unmarshalling response:", err)
 		return ValidateSubscription{}, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
-
-	// Log subscription details
-	s.Log.Info(fmt.Sprintf("Retrieved %d subscriptions (Total Cost: %d/%d)", subscriptionList.Total, subscriptionList.TotalCost, subscriptionList.MaxTotalCost))
-	for _, sub := range subscriptionList.Data {
-		s.Log.Info(fmt.Sprintf("  - ID: %s, Type: %s, Status: %s, Version: %s, Cost: %d", sub.ID, sub.Type, sub.Status, sub.Version, sub.Cost))
-	}
-
 	return subscriptionList, nil
 }
 
-// DeleteSubscriptions Removes all existing subscriptions
-// Validates headers exist before making API call for each subscription
-func (s *Subscription) DeleteSubscriptions(subs ValidateSubscription) error {
+// DeleteSubscription removes a single subscription by ID
+// Validates headers exist before making API call
+func (s *Subscription) DeleteSubscription(id string) error {
 	ctx := context.Background()
-	_, span := telemetry.StartSpan(ctx, "subscription.delete_all")
+	_, span := telemetry.StartSpan(ctx, "subscription.delete")
 	defer span.End()
 
-	if subs.Total > 0 {
-		for _, sub := range subs.Data {
-			// Validate Twitch API credentials before attempting deletion
-			headers, err := s.Secrets.BuildSecretHeaders()
-			if err != nil {
-				errMsg := fmt.Errorf("cannot delete subscription without valid Twitch credentials: %w", err)
-				s.Log.Error(fmt.Sprintf("Skipping subscription deletion - headers missing: %v", err), errMsg)
-				telemetry.RecordError(span, errMsg)
-				continue
-			}
+	// Validate Twitch API credentials before attempting deletion
+	headers, err := s.requestHeaders()
+	if err != nil {
+		errMsg := fmt.Errorf("cannot delete subscription without valid Twitch credentials: %w", err)
+		s.Log.Error("Cannot delete subscription - headers missing", errMsg)
+		telemetry.RecordError(span, errMsg)
+		return errMsg
+	}
 
-			deleteURL := fmt.Sprintf("%v?id=%v", URL, sub.ID)
-			req, err := http.NewRequestWithContext(ctx, "DELETE", deleteURL, nil)
-			if err != nil {
-				return errFailedToFormRequest
-			}
+	deleteURL := fmt.Sprintf("%v?id=%v", URL, url.QueryEscape(id))
+	req, err := http.NewRequestWithContext(ctx, "DELETE", deleteURL, nil)
+	if err != nil {
+		return errFailedToFormRequest
+	}
 
-			req.Header.Set("Content-Type", "application/json")
-			req.Header.Set("Authorization", "Bearer "+headers.Token)
-			req.Header.Set("Client-Id", headers.ClientID)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+headers.Token)
+	req.Header.Set("Client-Id", headers.ClientID)
 
-			s.Log.Info("Deleting subscription:" + sub.ID)
-			client := &http.Client{}
-			resp, err := client.Do(req)
-			if err != nil {
-				s.Log.Error("Error deleting subscription:", err)
-				continue
-			}
-			defer resp.Body.Close()
-			if resp.StatusCode == http.StatusNoContent {
-				s.Log.Info("Subscription deleted:" + sub.ID)
-			} else {
-				s.Log.Error("Failed to delete subscription", errFailedSubscriptionDeletion)
-			}
-		}
-	} else {
+	s.Log.Info("Deleting subscription:" + id)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		s.Log.Error("Error deleting subscription:", err)
+		telemetry.RecordError(span, err)
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		s.Log.Info("Subscription deleted:" + id)
+		return nil
+	case http.StatusNotFound:
+		telemetry.RecordError(span, ErrSubscriptionNotFound)
+		return ErrSubscriptionNotFound
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		deleteErr := fmt.Errorf("%w: status code %d", errFailedSubscriptionDeletion, resp.StatusCode)
+		s.Log.Error(fmt.Sprintf("Failed to delete subscription %s - Response: %s", id, string(body)), deleteErr)
+		telemetry.RecordError(span, deleteErr)
+		return deleteErr
+	}
+}
+
+// DeleteSubscriptions Removes every subscription in the list
+// Each deletion is attempted independently and reported in its own result
+func (s *Subscription) DeleteSubscriptions(subs ValidateSubscription) []DeletionResult {
+	results := make([]DeletionResult, 0, len(subs.Data))
+	if len(subs.Data) == 0 {
 		s.Log.Info("No subscriptions to delete")
+		return results
+	}
+
+	for _, sub := range subs.Data {
+		result := DeletionResult{ID: sub.ID, Type: sub.Type, Status: sub.Status}
+		if err := s.DeleteSubscription(sub.ID); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Deleted = true
+		}
+		results = append(results, result)
 	}
-	return nil
+	return results
 }
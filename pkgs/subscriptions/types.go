@@ -1,7 +1,25 @@
 // Package subscriptions handles all subscribe events on twitch
 package subscriptions
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
+
+// EventSub message types sent in the Twitch-Eventsub-Message-Type header
+const (
+	MessageTypeVerification = "webhook_callback_verification"
+	MessageTypeNotification = "notification"
+	MessageTypeRevocation   = "revocation"
+)
+
+// Revocation reasons reported in the subscription status of a revocation message
+const (
+	RevokedAuthorization        = "authorization_revoked"
+	RevokedUserRemoved          = "user_removed"
+	RevokedNotificationFailures = "notification_failures_exceeded"
+	RevokedVersionRemoved       = "version_removed"
+)
 
 // SubscriptionType represents a Twitch subscription type
 type SubscriptionType struct {
@@ -19,21 +37,88 @@ type SubscriptionData struct {
 	Condition struct {
 		BroadcasterUserID string `json:"broadcaster_user_id"`
 		UserID            string `json:"user_id"`
+		ModeratorUserID   string `json:"moderator_user_id,omitempty"`
 	} `json:"condition"`
 	CreatedAt time.Time `json:"created_at"`
 	Transport struct {
-		Method   string `json:"method"`
-		Callback string `json:"callback"`
+		Method    string `json:"method"`
+		Callback  string `json:"callback,omitempty"`
+		SessionID string `json:"session_id,omitempty"`
 	} `json:"transport"`
 	Cost int `json:"cost"`
 }
 
+// Healthy reports whether the subscription is delivering events or about to once verified
+func (d SubscriptionData) Healthy() bool {
+	return d.Status == "enabled" || d.Status == "webhook_callback_verification_pending"
+}
+
+// EventSubMessage is the envelope shared by every EventSub delivery.
+// Verification messages carry a Challenge, notifications carry an Event and
+// revocations only carry the Subscription whose Status holds the reason.
+type EventSubMessage struct {
+	Challenge    string           `json:"challenge,omitempty"`
+	Subscription SubscriptionData `json:"subscription"`
+	Event        json.RawMessage  `json:"event,omitempty"`
+}
+
 // ValidateSubscription represents the response from Twitch subscription validation
 type ValidateSubscription struct {
 	Data         []SubscriptionData `json:"data"`
 	Total        int                `json:"total"`
 	MaxTotalCost int                `json:"max_total_cost"`
 	TotalCost    int                `json:"total_cost"`
+	Pagination   struct {
+		Cursor string `json:"cursor,omitempty"`
+	} `json:"pagination"`
+}
+
+// SubscriptionFilter narrows a subscription listing, empty fields match everything
+type SubscriptionFilter struct {
+	Status string
+	Type   string
+}
+
+// DeletionResult reports the outcome of deleting a single subscription
+type DeletionResult struct {
+	ID      string `json:"id"`
+	Type    string `json:"type,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Deleted bool   `json:"deleted"`
+	Error   string `json:"error,omitempty"`
+}
+
+// SubscriptionSummary gives an overview of cost and health for a subscription listing
+type SubscriptionSummary struct {
+	TotalCost     int            `json:"total_cost"`
+	MaxTotalCost  int            `json:"max_total_cost"`
+	RemainingCost int            `json:"remaining_cost"`
+	ByStatus      map[string]int `json:"by_status"`
+	Failing       int            `json:"failing"`
+}
+
+// SubscriptionList is the admin API view of the current subscriptions
+type SubscriptionList struct {
+	Total   int                 `json:"total"`
+	Summary SubscriptionSummary `json:"summary"`
+	Data    []SubscriptionData  `json:"data"`
+}
+
+// Summarize counts the subscriptions per status and compares cost against the limit
+func (v ValidateSubscription) Summarize() SubscriptionSummary {
+	summary := SubscriptionSummary{
+		TotalCost:     v.TotalCost,
+		MaxTotalCost:  v.MaxTotalCost,
+		RemainingCost: v.MaxTotalCost - v.TotalCost,
+		ByStatus:      map[string]int{},
+	}
+	for _, sub := range v.Data {
+		summary.ByStatus[sub.Status]++
+		if !sub.Healthy() {
+			summary.Failing++
+		}
+	}
+	return summary
 }
 
 // EventLog represents a log entry for an event
@@ -49,26 +134,103 @@ type ChatMessage struct {
 	BroadcasterID string `json:"broadcaster_id"`
 	SenderID      string `json:"sender_id"`
 	Message       string `json:"message"`
+	// ReplyParentMessageID threads the message as a reply, empty for a regular message
+	ReplyParentMessageID string `json:"reply_parent_message_id,omitempty"`
 }
 
-// SubscribeEvent represents a subscription event
-type SubscribeEvent struct {
-	Challenge    string `json:"challenge"`
-	Subscription struct {
-		ID        string `json:"id"`
-		Status    string `json:"status"`
-		Type      string `json:"type"`
-		Version   string `json:"version"`
-		Cost      int    `json:"cost"`
-		Condition struct {
-			BroadcasterUserID string `json:"broadcaster_user_id"`
-		} `json:"condition"`
-		Transport struct {
-			Method   string `json:"method"`
-			Callback string `json:"callback"`
-		} `json:"transport"`
-		CreatedAt time.Time `json:"created_at"`
-	} `json:"subscription"`
+// ChatMessageResponse is the Helix response to sending a chat message.
+// A 200 does not mean the message was posted, IsSent is false when Twitch dropped it.
+type ChatMessageResponse struct {
+	Data []struct {
+		MessageID  string `json:"message_id"`
+		IsSent     bool   `json:"is_sent"`
+		DropReason *struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"drop_reason"`
+	} `json:"data"`
+}
+
+// HelixUser is a Twitch user as returned by Get Users
+type HelixUser struct {
+	ID          string `json:"id"`
+	Login       string `json:"login"`
+	DisplayName string `json:"display_name"`
+}
+
+// UsersResponse is the Helix response to Get Users
+type UsersResponse struct {
+	Data []HelixUser `json:"data"`
+}
+
+// ChannelInformation is a channel's title and category as returned by Get Channel Information
+type ChannelInformation struct {
+	BroadcasterID    string   `json:"broadcaster_id"`
+	BroadcasterLogin string   `json:"broadcaster_login"`
+	BroadcasterName  string   `json:"broadcaster_name"`
+	GameID           string   `json:"game_id"`
+	GameName         string   `json:"game_name"`
+	Title            string   `json:"title"`
+	Tags             []string `json:"tags"`
+}
+
+// ChannelInformationResponse is the Helix response to Get Channel Information
+type ChannelInformationResponse struct {
+	Data []ChannelInformation `json:"data"`
+}
+
+// Category is a Twitch category (game) as returned by Search Categories
+type Category struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	BoxArtURL string `json:"box_art_url"`
+}
+
+// CategoriesResponse is the Helix response to Search Categories
+type CategoriesResponse struct {
+	Data []Category `json:"data"`
+}
+
+// StreamsResponse is the Helix response to Get Streams, Data is empty when the channel is offline
+type StreamsResponse struct {
+	Data []struct {
+		ID        string    `json:"id"`
+		UserID    string    `json:"user_id"`
+		Type      string    `json:"type"`
+		StartedAt time.Time `json:"started_at"`
+	} `json:"data"`
+}
+
+// StreamMarkerResponse is the Helix response to Create Stream Marker, PositionSeconds is the offset into the stream
+type StreamMarkerResponse struct {
+	Data []struct {
+		ID              string `json:"id"`
+		Description     string `json:"description"`
+		PositionSeconds int    `json:"position_seconds"`
+	} `json:"data"`
+}
+
+// CreateClipResponse is the Helix response to Create Clip, the clip is still processing when it returns
+type CreateClipResponse struct {
+	Data []struct {
+		ID      string `json:"id"`
+		EditURL string `json:"edit_url"`
+	} `json:"data"`
+}
+
+// Clip is a finished clip as returned by Get Clips
+type Clip struct {
+	ID           string `json:"id"`
+	URL          string `json:"url"`
+	CreatorName  string `json:"creator_name"`
+	Title        string `json:"title"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// ClipsResponse is the Helix response to Get Clips, empty while a new clip is processing
+type ClipsResponse struct {
+	Data []Clip `json:"data"`
 }
 
 // ChatMessageEvent represents a chat message event from Twitch
@@ -110,7 +272,7 @@ type ChatMessageEvent struct {
 				} `json:"cheermote"`
 			} `json:"fragments"`
 		} `json:"message"`
-		Color       string    `json:"color"`
+		Color  string `json:"color"`
 		Badges []struct {
 			SetID string `json:"set_id"`
 			ID    string `json:"id"`
@@ -180,6 +342,99 @@ type CheerEvent struct {
 	} `json:"event"`
 }
 
+// PollChoice is a poll option with its votes, as sent by Helix and EventSub
+type PollChoice struct {
+	ID                 string `json:"id"`
+	Title              string `json:"title"`
+	Votes              int    `json:"votes"`
+	ChannelPointsVotes int    `json:"channel_points_votes"`
+}
+
+// Poll is a Twitch poll as returned by Helix and sent in channel.poll.* events.
+// Status is upper case in Helix (ACTIVE, COMPLETED, TERMINATED) and lower case in EventSub end events
+type Poll struct {
+	ID        string       `json:"id"`
+	Title     string       `json:"title"`
+	Choices   []PollChoice `json:"choices"`
+	Status    string       `json:"status,omitempty"`
+	StartedAt time.Time    `json:"started_at"`
+	EndsAt    *time.Time   `json:"ends_at,omitempty"`
+	EndedAt   *time.Time   `json:"ended_at,omitempty"`
+}
+
+// PollsResponse is the Helix response to Get Polls and Create Poll
+type PollsResponse struct {
+	Data []Poll `json:"data"`
+}
+
+// PollEvent represents a channel.poll.begin, progress or end event from Twitch
+type PollEvent struct {
+	Subscription struct {
+		ID        string `json:"id"`
+		Type      string `json:"type"`
+		Version   string `json:"version"`
+		Status    string `json:"status"`
+		Cost      int    `json:"cost"`
+		Condition struct {
+			BroadcasterUserID string `json:"broadcaster_user_id"`
+		} `json:"condition"`
+		Transport struct {
+			Method   string `json:"method"`
+			Callback string `json:"callback"`
+		} `json:"transport"`
+		CreatedAt time.Time `json:"created_at"`
+	} `json:"subscription"`
+	Event Poll `json:"event"`
+}
+
+// PredictionOutcome is a prediction option with the users and channel points on it
+type PredictionOutcome struct {
+	ID            string `json:"id"`
+	Title         string `json:"title"`
+	Color         string `json:"color"`
+	Users         int    `json:"users"`
+	ChannelPoints int    `json:"channel_points"`
+}
+
+// Prediction is a Twitch prediction as returned by Helix and sent in channel.prediction.* events.
+// Status is upper case in Helix (ACTIVE, LOCKED, RESOLVED, CANCELED) and lower case in EventSub end events
+type Prediction struct {
+	ID               string              `json:"id"`
+	Title            string              `json:"title"`
+	Outcomes         []PredictionOutcome `json:"outcomes"`
+	WinningOutcomeID string              `json:"winning_outcome_id,omitempty"`
+	Status           string              `json:"status,omitempty"`
+	StartedAt        time.Time           `json:"started_at"`
+	LocksAt          *time.Time          `json:"locks_at,omitempty"`
+	LockedAt         *time.Time          `json:"locked_at,omitempty"`
+	EndedAt          *time.Time          `json:"ended_at,omitempty"`
+}
+
+// PredictionsResponse is the Helix response to Get Predictions, Create Prediction and End Prediction
+type PredictionsResponse struct {
+	Data []Prediction `json:"data"`
+}
+
+// PredictionEvent represents a channel.prediction.begin, progress, lock or end event from Twitch
+type PredictionEvent struct {
+	Subscription struct {
+		ID        string `json:"id"`
+		Type      string `json:"type"`
+		Version   string `json:"version"`
+		Status    string `json:"status"`
+		Cost      int    `json:"cost"`
+		Condition struct {
+			BroadcasterUserID string `json:"broadcaster_user_id"`
+		} `json:"condition"`
+		Transport struct {
+			Method   string `json:"method"`
+			Callback string `json:"callback"`
+		} `json:"transport"`
+		CreatedAt time.Time `json:"created_at"`
+	} `json:"subscription"`
+	Event Prediction `json:"event"`
+}
+
 // RewardEvent represents a reward redemption event from Twitch
 type RewardEvent struct {
 	Subscription struct {
@@ -39,14 +39,33 @@ var (
 	CacheOperationTotal metric.Int64Counter
 
 	// Command and message metrics
-	CommandExecutedTotal metric.Int64Counter
-	MessageSentTotal     metric.Int64Counter
+	CommandExecutedTotal   metric.Int64Counter
+	CommandDeniedTotal     metric.Int64Counter
+	CommandSuppressedTotal metric.Int64Counter
+	MessageSentTotal       metric.Int64Counter
+	MessageRetryTotal      metric.Int64Counter
+	MessageQueueDepth      metric.Int64Gauge
+	MessageQueueLatency    metric.Float64Histogram
+
+	// Moderation metrics
+	ModerationActionTotal metric.Int64Counter
+
+	// Clip metrics
+	ClipTotal metric.Int64Counter
 
 	// Notification metrics
 	NotificationSentTotal metric.Int64Counter
 
 	// Spotify operation metrics
 	SpotifyOperationTotal metric.Int64Counter
+
+	// EventSub webhook metrics
+	EventSubRejectedTotal metric.Int64Counter
+	EventSubRevokedTotal  metric.Int64Counter
+	WebSocketReconnects   metric.Int64Counter
+
+	// Subscription reconcile metrics
+	ReconcileActionTotal metric.Int64Counter
 )
 
 // InitMetrics initializes all OTEL metrics
@@ -168,6 +187,22 @@ func InitMetrics() error {
 		return err
 	}
 
+	CommandDeniedTotal, err = meter.Int64Counter(
+		"twitch.command_denied_total",
+		metric.WithDescription("Chat commands rejected because the chatter lacks the required permission"),
+	)
+	if err != nil {
+		return err
+	}
+
+	CommandSuppressedTotal, err = meter.Int64Counter(
+		"twitch.command_cooldown_suppressed_total",
+		metric.WithDescription("Chat commands suppressed because they are on cooldown"),
+	)
+	if err != nil {
+		return err
+	}
+
 	MessageSentTotal, err = meter.Int64Counter(
 		"twitch.message_sent_total",
 		metric.WithDescription("Messages sent to Twitch chat by result"),
@@ -176,6 +211,47 @@ func InitMetrics() error {
 		return err
 	}
 
+	ModerationActionTotal, err = meter.Int64Counter(
+		"twitch.moderation_action_total",
+		metric.WithDescription("Moderation actions taken on chat messages by rule, action and result"),
+	)
+	if err != nil {
+		return err
+	}
+
+	ClipTotal, err = meter.Int64Counter(
+		"twitch.clip_total",
+		metric.WithDescription("Clips requested from chat or channel points by source and result"),
+	)
+	if err != nil {
+		return err
+	}
+
+	MessageRetryTotal, err = meter.Int64Counter(
+		"twitch.message_retry_total",
+		metric.WithDescription("Chat message sends retried after a 429, 5xx or network error"),
+	)
+	if err != nil {
+		return err
+	}
+
+	MessageQueueDepth, err = meter.Int64Gauge(
+		"twitch.message_queue_depth",
+		metric.WithDescription("Chat messages waiting in the outbound queue"),
+	)
+	if err != nil {
+		return err
+	}
+
+	MessageQueueLatency, err = meter.Float64Histogram(
+		"twitch.message_queue_latency_seconds",
+		metric.WithDescription("Time from queueing a chat message until Twitch accepted it"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return err
+	}
+
 	// Notification metrics
 	NotificationSentTotal, err = meter.Int64Counter(
 		"twitch.notification_sent_total",
@@ -194,6 +270,40 @@ func InitMetrics() error {
 		return err
 	}
 
+	// EventSub webhook metrics
+	EventSubRejectedTotal, err = meter.Int64Counter(
+		"twitch.eventsub_rejected_total",
+		metric.WithDescription("EventSub webhook deliveries rejected by reason"),
+	)
+	if err != nil {
+		return err
+	}
+
+	EventSubRevokedTotal, err = meter.Int64Counter(
+		"twitch.eventsub_revoked_total",
+		metric.WithDescription("EventSub subscriptions revoked by type and reason"),
+	)
+	if err != nil {
+		return err
+	}
+
+	WebSocketReconnects, err = meter.Int64Counter(
+		"twitch.eventsub_websocket_reconnect_total",
+		metric.WithDescription("EventSub websocket reconnects by reason"),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Subscription reconcile metrics
+	ReconcileActionTotal, err = meter.Int64Counter(
+		"twitch.eventsub_reconcile_action_total",
+		metric.WithDescription("Subscription reconcile actions applied by action and result"),
+	)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -322,6 +432,76 @@ func IncrementCommandExecuted(ctx context.Context, command string) {
 	}
 }
 
+// IncrementCommandDenied records a chat command rejected for missing permission with the command and required level labels.
+func IncrementCommandDenied(ctx context.Context, command, required string) {
+	if CommandDeniedTotal != nil {
+		CommandDeniedTotal.Add(ctx, 1,
+			metric.WithAttributes(
+				attribute.String("command", command),
+				attribute.String("required", required),
+			),
+		)
+	}
+}
+
+// IncrementCommandSuppressed records a chat command suppressed by a cooldown with the command and scope (global, user) labels.
+func IncrementCommandSuppressed(ctx context.Context, command, scope string) {
+	if CommandSuppressedTotal != nil {
+		CommandSuppressedTotal.Add(ctx, 1,
+			metric.WithAttributes(
+				attribute.String("command", command),
+				attribute.String("scope", scope),
+			),
+		)
+	}
+}
+
+// IncrementModerationAction records a moderation action with the rule, action (delete, timeout, ban) and result labels.
+func IncrementModerationAction(ctx context.Context, rule, action, result string) {
+	if ModerationActionTotal != nil {
+		ModerationActionTotal.Add(ctx, 1,
+			metric.WithAttributes(
+				attribute.String("rule", rule),
+				attribute.String("action", action),
+				attribute.String("result", result),
+			),
+		)
+	}
+}
+
+// IncrementClip records a clip request with the source (command, reward) and result (success, error, timeout) labels.
+func IncrementClip(ctx context.Context, source, result string) {
+	if ClipTotal != nil {
+		ClipTotal.Add(ctx, 1,
+			metric.WithAttributes(
+				attribute.String("source", source),
+				attribute.String("result", result),
+			),
+		)
+	}
+}
+
+// IncrementMessageRetry records a retried chat message send.
+func IncrementMessageRetry(ctx context.Context) {
+	if MessageRetryTotal != nil {
+		MessageRetryTotal.Add(ctx, 1)
+	}
+}
+
+// RecordMessageQueueDepth records the number of chat messages waiting to be sent.
+func RecordMessageQueueDepth(ctx context.Context, depth int64) {
+	if MessageQueueDepth != nil {
+		MessageQueueDepth.Record(ctx, depth)
+	}
+}
+
+// RecordMessageQueueLatency records how long a chat message waited before Twitch accepted it.
+func RecordMessageQueueLatency(ctx context.Context, seconds float64) {
+	if MessageQueueLatency != nil {
+		MessageQueueLatency.Record(ctx, seconds)
+	}
+}
+
 // IncrementMessageSent records a Twitch chat message send attempt with result label.
 func IncrementMessageSent(ctx context.Context, result string) {
 	if MessageSentTotal != nil {
@@ -356,3 +536,49 @@ func IncrementSpotifyOperation(ctx context.Context, operation, result string) {
 		)
 	}
 }
+
+// IncrementEventSubRejected records an EventSub webhook delivery rejected before reaching a handler.
+func IncrementEventSubRejected(ctx context.Context, reason string) {
+	if EventSubRejectedTotal != nil {
+		EventSubRejectedTotal.Add(ctx, 1,
+			metric.WithAttributes(
+				attribute.String("reason", reason),
+			),
+		)
+	}
+}
+
+// IncrementSubscriptionRevoked records Twitch revoking an EventSub subscription with type and reason labels.
+func IncrementSubscriptionRevoked(ctx context.Context, subscriptionType, reason string) {
+	if EventSubRevokedTotal != nil {
+		EventSubRevokedTotal.Add(ctx, 1,
+			metric.WithAttributes(
+				attribute.String("subscription_type", subscriptionType),
+				attribute.String("reason", reason),
+			),
+		)
+	}
+}
+
+// IncrementReconcileAction records a subscription reconcile action with action and result labels.
+func IncrementReconcileAction(ctx context.Context, action, result string) {
+	if ReconcileActionTotal != nil {
+		ReconcileActionTotal.Add(ctx, 1,
+			metric.WithAttributes(
+				attribute.String("action", action),
+				attribute.String("result", result),
+			),
+		)
+	}
+}
+
+// IncrementWebSocketReconnect records the EventSub websocket reconnecting with a reason label.
+func IncrementWebSocketReconnect(ctx context.Context, reason string) {
+	if WebSocketReconnects != nil {
+		WebSocketReconnects.Add(ctx, 1,
+			metric.WithAttributes(
+				attribute.String("reason", reason),
+			),
+		)
+	}
+}